@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import "syscall"
+
+// checkWritable returns an error if the current user may not write to path.
+func checkWritable(path string) error {
+	return syscall.Access(path, 0x2)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package main
+
+import "os"
+
+// checkWritable returns an error if path is marked read-only. Windows ACLs
+// are not inspected.
+func checkWritable(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if stat.Mode().Perm()&0200 == 0 {
+		return os.ErrPermission
+	}
+	return nil
+}
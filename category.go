@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func listCategories(c *cli.Context) error {
+	counts := make(map[string]int)
+	for _, p := range db.GetProjectList(c.Bool("all")) {
+		categories := getProjectCategories(c, p)
+		for i := range categories {
+			counts[strings.Join(categories[:i+1], "/")]++
+		}
+	}
+
+	type categoryRecord struct {
+		Name     string
+		Projects int
+	}
+	records := []categoryRecord{}
+	for name, count := range counts {
+		records = append(records, categoryRecord{Name: name, Projects: count})
+	}
+	sort.Slice(records, func(a int, b int) bool {
+		return records[a].Name < records[b].Name
+	})
+
+	if c.String("format") != "" {
+		return writeFormatted(c, records)
+	}
+
+	retval := ""
+	for _, r := range records {
+		retval = fmt.Sprintf("%s%s: %d\n", retval, r.Name, r.Projects)
+	}
+	log(c, `Categories
+----------
+%s`, retval)
+	return nil
+}
+
+func renameCategory(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return exitErrorWrapper("invalid number of arguments, expected 2")
+	}
+	return relocateCategory(c, splitCategories(c.Args()[:1]), splitCategories(c.Args()[1:]))
+}
+
+func moveCategory(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return exitErrorWrapper("invalid number of arguments, expected 2")
+	}
+	from := splitCategories(c.Args()[:1])
+	if len(from) == 0 {
+		return exitErrorWrapper("no category given")
+	}
+	// A destination of / moves the category to the top level.
+	to := append(splitCategories(c.Args()[1:]), from[len(from)-1])
+	return relocateCategory(c, from, to)
+}
+
+// relocateCategory moves the directory of category from to to and updates
+// every project inside it. If anything fails the directory and the projects
+// are put back.
+func relocateCategory(c *cli.Context, from []string, to []string) error {
+	if len(from) == 0 || len(to) == 0 {
+		return exitErrorWrapper("no category given")
+	}
+	base := absPath(getBaseDir(c))
+	src := filepath.Join(base, filepath.Join(from...))
+	dst := filepath.Join(base, filepath.Join(to...))
+	name, newName := strings.Join(from, "/"), strings.Join(to, "/")
+
+	if isDir, err := pathIsDir(src); err != nil || !isDir {
+		return exitErrorWrapper("category '%s' does not exist in %s", name, base)
+	}
+	if dst == src {
+		return exitErrorWrapper("category '%s' is already at %s", name, newName)
+	}
+	if isBelow(dst, src) {
+		return exitErrorWrapper("can't move category '%s' into itself", name)
+	}
+	exists, err := pathExists(dst)
+	if err != nil {
+		return exitErrorWrapper("could not determine if path exists: %s", err.Error())
+	}
+	if exists {
+		return exitErrorWrapper("path '%s' already exists", dst)
+	}
+
+	var affected []db.Project
+	for _, p := range db.GetProjects() {
+		if isBelow(absPath(p.Path), src) {
+			affected = append(affected, p)
+		}
+	}
+
+	created, err := createParents(dst)
+	if err != nil {
+		return exitErrorWrapper("could not create directory: %s", err.Error())
+	}
+	rollbackParents := func() {
+		if created != "" {
+			os.RemoveAll(created)
+		}
+	}
+
+	if err := moveDir(src, dst); err != nil {
+		rollbackParents()
+		return exitErrorWrapper("could not move category directory: %s", err.Error())
+	}
+
+	for _, p := range affected {
+		updateProjectPath(c, p.Name, dst+strings.TrimPrefix(absPath(p.Path), src))
+	}
+	if err := db.Save(); err != nil {
+		for _, p := range affected {
+			restoreProject(p)
+		}
+		if moveErr := moveDir(dst, src); moveErr != nil {
+			return exitErrorWrapper("could not save database: %s, and could not move the category back from %s: %s", err.Error(), dst, moveErr.Error())
+		}
+		rollbackParents()
+		return exitErrorWrapper("could not save database, move undone: %s", err.Error())
+	}
+
+	removeEmptyParents(c, src)
+	log(c, "Moved category '%s' to '%s', %d projects updated", name, newName, len(affected))
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/detect"
+	"github.com/Tebro/prj/diskusage"
+	"github.com/Tebro/prj/manifest"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// excluded reports whether the artefact directory rel is kept by one of the
+// clean.exclude patterns of a manifest. A pattern keeps the directories it
+// matches and everything below them.
+func excluded(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.Trim(filepath.ToSlash(pattern), "/")
+		if ok, _ := path.Match(pattern, rel); ok || strings.HasPrefix(rel, pattern+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// projectArtefacts returns the artefact directories of p that 'prj clean'
+// considers. Only the ignored ones are removed without --force.
+func projectArtefacts(p db.Project) ([]detect.Artefact, error) {
+	if p.Detection == nil {
+		return nil, nil
+	}
+	m, err := manifest.Load(p.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var artefacts []detect.Artefact
+	for _, a := range detect.Artefacts(p.Path, p.Detection.BuildSystems) {
+		if !excluded(a.Path, m.CleanExclude) {
+			artefacts = append(artefacts, a)
+		}
+	}
+	return artefacts, nil
+}
+
+// cleanTarget is an artefact directory about to be removed.
+type cleanTarget struct {
+	project string
+	rel     string
+	path    string
+	size    int64
+}
+
+func cleanProjects(c *cli.Context) error {
+	projects, err := selectTargets(c, db.GetProjectList(c.Bool("all")))
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return exitErrorWrapper("no projects selected")
+	}
+	// The artefacts depend on the build systems.
+	projects = detectProjects(projects, false)
+
+	cache := diskusage.Load(sizeCachePath())
+	var targets []cleanTarget
+	var total int64
+	failed, skipped := 0, 0
+	for _, p := range projects {
+		if isDir, _ := pathIsDir(p.Path); !isDir {
+			continue
+		}
+		artefacts, err := projectArtefacts(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: skipped, could not read %s: %s\n", p.Name, manifest.FileName, err.Error())
+			failed++
+			continue
+		}
+		for _, a := range artefacts {
+			dir := filepath.Join(p.Path, filepath.FromSlash(a.Path))
+			size := cache.Measure(dir).Bytes
+			if !a.Ignored && !c.Bool("force") {
+				log(c, "%s: %s (%s), skipped, the project has neither .git nor .gitignore to check it against", p.Name, a.Path, formatSize(size))
+				skipped++
+				continue
+			}
+			targets = append(targets, cleanTarget{project: p.Name, rel: a.Path, path: dir, size: size})
+			total += size
+		}
+	}
+	cache.Save()
+	if skipped > 0 {
+		log(c, "Use --force to remove the %d skipped directories as well", skipped)
+	}
+
+	if len(targets) == 0 {
+		log(c, "Nothing to clean")
+		if failed > 0 {
+			return exitErrorWrapper("%d projects could not be checked", failed)
+		}
+		return nil
+	}
+
+	for _, t := range targets {
+		log(c, "%s: %s (%s)", t.project, t.rel, formatSize(t.size))
+	}
+	if c.Bool("dry-run") {
+		log(c, "Would free %s in %d directories", formatSize(total), len(targets))
+		return nil
+	}
+	if !c.Bool("yes") {
+		if !stdinIsTerminal() {
+			log(c, "Run again with --yes to remove them")
+			return nil
+		}
+		if !confirm(c, fmt.Sprintf("Remove %d directories, freeing %s?", len(targets), formatSize(total))) {
+			return nil
+		}
+	}
+
+	var freed int64
+	for _, t := range targets {
+		if err := os.RemoveAll(t.path); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: could not remove %s: %s\n", t.project, t.rel, err.Error())
+			failed++
+			continue
+		}
+		freed += t.size
+	}
+	log(c, "Freed %s", formatSize(freed))
+	if failed > 0 {
+		return exitErrorWrapper("%d directories or projects could not be cleaned", failed)
+	}
+	return nil
+}
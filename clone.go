@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/hooks"
+	"github.com/Tebro/prj/repourl"
+	"github.com/Tebro/prj/vcs"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// cloneCategories renders the configured clone layout for u into a list of
+// categories. Empty segments, e.g. from a missing owner, are dropped.
+func cloneCategories(u repourl.URL) ([]string, error) {
+	layout := db.GetConfigCloneLayout()
+	tmpl, err := template.New("layout").Option("missingkey=error").Parse(layout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CloneLayout '%s': %s", layout, err.Error())
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, u); err != nil {
+		return nil, fmt.Errorf("invalid CloneLayout '%s': %s", layout, err.Error())
+	}
+
+	var categories []string
+	for _, segment := range strings.Split(filepath.ToSlash(buf.String()), "/") {
+		if segment != "" && segment != "." && segment != ".." {
+			categories = append(categories, segment)
+		}
+	}
+	return categories, nil
+}
+
+func cloneProject(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return exitErrorWrapper("invalid number of arguments, expected 1")
+	}
+
+	u, err := repourl.Parse(c.Args()[0])
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+
+	repository, err := vcs.Get(c.String("vcs"))
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+	if err := vcs.Available(repository); err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+
+	categories := c.StringSlice("categories")
+	if len(categories) == 0 {
+		categories, err = cloneCategories(u)
+		if err != nil {
+			return exitErrorWrapper("%s", err.Error())
+		}
+	}
+
+	projectName := u.Repo
+	if len(c.String("name")) > 0 {
+		projectName = c.String("name")
+	}
+	if _, err := db.GetProjectDir(projectName); err == nil {
+		return exitErrorWrapper("project '%s' exists, use --name to pick another name", projectName)
+	}
+
+	if err := createBaseDirIfNotExists(c); err != nil {
+		return exitErrorWrapper("could not find or create base dir : %s", err.Error())
+	}
+
+	finalPath := filepath.Join(getBaseDir(c), filepath.Join(categories...), u.Repo)
+	exists, err := pathExists(finalPath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return exitErrorWrapper("path %s exists", finalPath)
+	}
+
+	hp := hookProject(c, projectName, finalPath, categories)
+	if err := runPreHook(c, hooks.New, hp); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(finalPath), 0755); err != nil {
+		return exitErrorWrapper("could not create category directories: %s", err.Error())
+	}
+
+	opts := vcs.CloneOptions{
+		Depth:  c.Int("depth"),
+		Branch: c.String("branch"),
+		Stdout: c.App.Writer,
+		Stderr: os.Stderr,
+	}
+	if err := repository.Clone(u.CloneURL(opts.Depth > 0), finalPath, opts); err != nil {
+		os.RemoveAll(finalPath)
+		return exitErrorWrapper("could not clone %s: %s", u.Raw, err.Error())
+	}
+
+	err = db.AddProject(projectName, finalPath, categories)
+	if err != nil {
+		return exitErrorWrapper("could not add project: %s", err.Error())
+	}
+	detectRegistered(projectName)
+
+	log(c, "Cloned %s into %s as project '%s'", u.Raw, finalPath, projectName)
+
+	runPostHook(c, hooks.New, hp)
+	return nil
+}
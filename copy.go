@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/fsutil"
+	"github.com/Tebro/prj/vcs"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func copyFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "exclude-ignored",
+			Usage: "Do not copy files ignored by .gitignore",
+		},
+		cli.BoolFlag{
+			Name:  "no-vcs",
+			Usage: "Do not copy version control metadata such as .git",
+		},
+		cli.BoolFlag{
+			Name:  "reinit",
+			Usage: "Do not copy version control metadata, create a fresh repository of the same kind instead",
+		},
+	}
+}
+
+// copySource is a directory a new project is copied from.
+type copySource struct {
+	Path string
+	// VCS is the version control system of the source, if any
+	VCS vcs.VCS
+	tmp string
+}
+
+// Close removes the temporary directory an archive was extracted into.
+func (s *copySource) Close() {
+	if s.tmp != "" {
+		os.RemoveAll(s.tmp)
+	}
+}
+
+// resolveCopySource finds the directory to copy from, which is the path of a
+// registered project, a directory or an archive that is extracted to a
+// temporary directory.
+func resolveCopySource(from string, dst string) (*copySource, error) {
+	path := from
+	if projectDir, err := db.GetProjectDir(from); err == nil {
+		path = projectDir
+	}
+
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("'%s' is neither a project, a directory nor an archive", from)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	source := &copySource{Path: path}
+	switch {
+	case stat.IsDir():
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if dst == abs || strings.HasPrefix(dst, abs+string(os.PathSeparator)) {
+			return nil, fmt.Errorf("cannot copy %s into itself", from)
+		}
+	case fsutil.IsArchive(path):
+		source.tmp, err = ioutil.TempDir("", "prj-copy")
+		if err != nil {
+			return nil, err
+		}
+		if err := fsutil.Extract(path, source.tmp); err != nil {
+			source.Close()
+			return nil, fmt.Errorf("could not extract %s: %s", from, err.Error())
+		}
+		source.Path = fsutil.ContentRoot(source.tmp)
+	default:
+		return nil, fmt.Errorf("'%s' is not a directory or a supported archive (.tar, .tar.gz, .tgz, .zip)", from)
+	}
+
+	source.VCS, _ = vcs.Detect(source.Path)
+	return source, nil
+}
+
+func getCopyOptions(c *cli.Context) fsutil.CopyOptions {
+	return fsutil.CopyOptions{
+		SkipVCS:   c.Bool("no-vcs") || c.Bool("reinit"),
+		Gitignore: c.Bool("exclude-ignored"),
+	}
+}
+
+func copyProject(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return exitErrorWrapper("invalid number of arguments, expected 2")
+	}
+
+	return createProject(c, c.Args()[1], c.Args()[0])
+}
@@ -7,40 +7,147 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 )
 
 var configPath = filepath.Join(os.Getenv("HOME"), ".prj")
 var dbPath = filepath.Join(configPath, "db.json")
+var templatesPath = filepath.Join(configPath, "templates")
+var hooksPath = filepath.Join(configPath, "hooks")
+var cachePath = filepath.Join(configPath, "cache")
 var database Database
 
+// DefaultCloneLayout is the category layout used by clone when none is configured
+const DefaultCloneLayout = "{{.Host}}/{{.Owner}}"
+
 // Config contains various configuratble variables
 type Config struct {
 	BaseDir            string
 	AlwaysGit          bool
 	EditorInBackground bool
+	CloneLayout        string
+	DefaultBranch      string
+	InitialCommit      bool
+	Author             string
+	ExtraBaseDirs      []string               `json:",omitempty"`
+	GitIdentities      map[string]GitIdentity `json:",omitempty"`
+	// AutoTags maps a language or build system to the tags given to the
+	// projects it is detected in.
+	AutoTags map[string][]string `json:",omitempty"`
+}
+
+// GitIdentity is the git author used for new projects in a category
+type GitIdentity struct {
+	Name  string
+	Email string
+}
+
+func (i GitIdentity) String() string {
+	return fmt.Sprintf("%s <%s>", i.Name, i.Email)
 }
 
 func (c Config) String() string {
-	return fmt.Sprintf(
-		`Configuration options
+	retval := `Configuration options
 Name: value
 -----------
-BaseDir: %s
-AlwaysGit: %t
-EditorInBackground: %t
-`, c.BaseDir, c.AlwaysGit, c.EditorInBackground)
+`
+	for _, o := range c.Options() {
+		retval = fmt.Sprintf("%s%s: %s\n", retval, o.Name, o.Value)
+	}
+	return retval
+}
+
+// Option is a single configuration option and its value formatted the way it
+// is set with 'prj config set'.
+type Option struct {
+	Name  string
+	Value string
+}
+
+// Options returns all configuration options in a stable order.
+func (c Config) Options() []Option {
+	options := []Option{
+		{"BaseDir", c.BaseDir},
+		{"AlwaysGit", fmt.Sprint(c.AlwaysGit)},
+		{"EditorInBackground", fmt.Sprint(c.EditorInBackground)},
+		{"CloneLayout", c.CloneLayout},
+		{"DefaultBranch", c.DefaultBranch},
+		{"InitialCommit", fmt.Sprint(c.InitialCommit)},
+		{"Author", c.Author},
+		{"ExtraBaseDirs", strings.Join(c.ExtraBaseDirs, ",")},
+	}
+
+	var categories []string
+	for category := range c.GitIdentities {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		options = append(options, Option{"GitIdentity." + category, c.GitIdentities[category].String()})
+	}
+
+	var detected []string
+	for name := range c.AutoTags {
+		detected = append(detected, name)
+	}
+	sort.Strings(detected)
+	for _, name := range detected {
+		options = append(options, Option{"AutoTag." + name, strings.Join(c.AutoTags[name], ",")})
+	}
+
+	return options
 }
 
 // Project describes a Project, contains a name and a path
 type Project struct {
-	Name string
-	Path string
+	Name        string
+	Path        string
+	Categories  []string     `json:",omitempty"`
+	Tags        []string     `json:",omitempty"`
+	Description string       `json:",omitempty"`
+	Archived    bool         `json:",omitempty"`
+	Fingerprint *Fingerprint `json:",omitempty"`
+	Detection   *Detection   `json:",omitempty"`
+	// RemovedTags are the tags the user removed, the AutoTag rules don't add
+	// them again.
+	RemovedTags []string `json:",omitempty"`
+	// HooksAllowed is the hash of the .prj.toml whose hooks the user allowed
+	// to run, see 'prj hooks allow'.
+	HooksAllowed string `json:",omitempty"`
+	// Created is when the project was registered, Accessed when it was last
+	// visited with goto. Both are zero for projects from older versions.
+	Created     time.Time
+	Accessed    time.Time
+	AccessCount int `json:",omitempty"`
+}
+
+// Fingerprint identifies a project directory independently of its path, so
+// the project can be found again after it was moved.
+type Fingerprint struct {
+	// RootCommit is the first commit of the repository, shared by all clones.
+	RootCommit string `json:",omitempty"`
+	// Device and Inode identify the directory as long as it stays on the same
+	// file system.
+	Device uint64 `json:",omitempty"`
+	Inode  uint64 `json:",omitempty"`
+	// MarkerID is a random id stored in the version control metadata.
+	MarkerID string `json:",omitempty"`
+}
+
+// Detection is the cached classification of a project.
+type Detection struct {
+	Languages    []string `json:",omitempty"`
+	BuildSystems []string `json:",omitempty"`
+	// Refreshed is when the project was last classified.
+	Refreshed time.Time
 }
 
 // Database is the top level object that the software uses to persist data and configuration
 type Database struct {
 	Config   Config
 	Projects map[string]Project
+	Queries  map[string]string `json:",omitempty"`
 }
 
 func serializeDatabase(db Database) ([]byte, error) {
@@ -105,6 +212,7 @@ func createDefaultDatabase() Database {
 			BaseDir:            fmt.Sprintf("%s/%s", os.Getenv("HOME"), "Projects"),
 			AlwaysGit:          false,
 			EditorInBackground: false,
+			CloneLayout:        DefaultCloneLayout,
 		},
 		Projects: make(map[string]Project),
 	}
@@ -142,13 +250,36 @@ func PrepareForShutdown() {
 	saveDatabase(database)
 }
 
+// Save writes the database to disk right away and reports failures instead of
+// panicking, for operations that have to be undone if the database can't be
+// written.
+func Save() error {
+	data, err := serializeDatabase(database)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dbPath, data, 0644)
+}
+
+// GetConfigOptions returns the configuration options from the database.
+func GetConfigOptions() []Option {
+	return database.Config.Options()
+}
+
 // GetConfigList returns the Config objects String representation from the database.
 func GetConfigList() string {
 	return database.Config.String()
 }
 
 // SetConfigOption is a wrapper for modifying the Config part of the database.
-func SetConfigOption(key string, value string) {
+func SetConfigOption(key string, value string) error {
+	if strings.HasPrefix(key, "GitIdentity.") {
+		return setGitIdentity(strings.TrimPrefix(key, "GitIdentity."), value)
+	}
+	if strings.HasPrefix(key, "AutoTag.") {
+		return setAutoTag(strings.TrimPrefix(key, "AutoTag."), value)
+	}
+
 	switch key {
 	case "BaseDir":
 		database.Config.BaseDir = value
@@ -160,7 +291,97 @@ func SetConfigOption(key string, value string) {
 	case "EditorInBackground":
 		database.Config.EditorInBackground = value == "true"
 		break
+	case "CloneLayout":
+		database.Config.CloneLayout = value
+		break
+	case "DefaultBranch":
+		database.Config.DefaultBranch = value
+		break
+	case "InitialCommit":
+		database.Config.InitialCommit = value == "true"
+		break
+	case "Author":
+		database.Config.Author = value
+		break
+	case "ExtraBaseDirs":
+		database.Config.ExtraBaseDirs = nil
+		for _, dir := range strings.Split(value, ",") {
+			if dir = strings.TrimSpace(dir); dir != "" {
+				database.Config.ExtraBaseDirs = append(database.Config.ExtraBaseDirs, dir)
+			}
+		}
+		break
+	default:
+		return fmt.Errorf("unknown configuration option '%s'", key)
 	}
+	return nil
+}
+
+// setGitIdentity sets the identity for category from a "Name <email>" value, an empty value removes it
+func setGitIdentity(category string, value string) error {
+	category = strings.Trim(category, "/")
+	if category == "" {
+		return fmt.Errorf("GitIdentity needs a category, e.g. GitIdentity.work")
+	}
+
+	if value == "" {
+		delete(database.Config.GitIdentities, category)
+		return nil
+	}
+
+	start := strings.Index(value, "<")
+	end := strings.LastIndex(value, ">")
+	if start < 0 || end < start {
+		return fmt.Errorf("invalid git identity '%s', expected 'Name <email>'", value)
+	}
+
+	if database.Config.GitIdentities == nil {
+		database.Config.GitIdentities = make(map[string]GitIdentity)
+	}
+	database.Config.GitIdentities[category] = GitIdentity{
+		Name:  strings.TrimSpace(value[:start]),
+		Email: strings.TrimSpace(value[start+1 : end]),
+	}
+	return nil
+}
+
+// setAutoTag sets the comma separated tags for projects where detected, a language or build system, is found, an empty value removes it
+func setAutoTag(detected string, value string) error {
+	if detected == "" {
+		return fmt.Errorf("AutoTag needs a language or build system, e.g. AutoTag.Go")
+	}
+
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		delete(database.Config.AutoTags, detected)
+		return nil
+	}
+
+	if database.Config.AutoTags == nil {
+		database.Config.AutoTags = make(map[string][]string)
+	}
+	database.Config.AutoTags[detected] = tags
+	return nil
+}
+
+// GetTemplatesDir returns the directory where templates are stored
+func GetTemplatesDir() string {
+	return templatesPath
+}
+
+// GetHooksDir returns the directory where global hooks are stored
+func GetHooksDir() string {
+	return hooksPath
+}
+
+// GetCacheDir returns the directory where caches that can be rebuilt at any time are stored
+func GetCacheDir() string {
+	return cachePath
 }
 
 // GetConfigBaseDir returns the BaseDir option from the configuration
@@ -178,13 +399,56 @@ func GetConfigEditorInBackground() bool {
 	return database.Config.EditorInBackground
 }
 
+// GetConfigCloneLayout returns the CloneLayout option from the configuration
+func GetConfigCloneLayout() string {
+	if database.Config.CloneLayout == "" {
+		return DefaultCloneLayout
+	}
+	return database.Config.CloneLayout
+}
+
+// GetConfigDefaultBranch returns the DefaultBranch option from the configuration
+func GetConfigDefaultBranch() string {
+	return database.Config.DefaultBranch
+}
+
+// GetConfigInitialCommit returns the InitialCommit option from the configuration
+func GetConfigInitialCommit() bool {
+	return database.Config.InitialCommit
+}
+
+// GetConfigExtraBaseDirs returns the ExtraBaseDirs option from the configuration
+func GetConfigExtraBaseDirs() []string {
+	return database.Config.ExtraBaseDirs
+}
+
+// GetConfigAuthor returns the Author option from the configuration
+func GetConfigAuthor() string {
+	return database.Config.Author
+}
+
+// GetConfigAutoTags returns the AutoTag rules from the configuration
+func GetConfigAutoTags() map[string][]string {
+	return database.Config.AutoTags
+}
+
+// GetGitIdentity returns the git identity configured for the most specific of the given categories
+func GetGitIdentity(categories []string) (GitIdentity, bool) {
+	for i := len(categories); i > 0; i-- {
+		if identity, ok := database.Config.GitIdentities[strings.Join(categories[:i], "/")]; ok {
+			return identity, true
+		}
+	}
+	return GitIdentity{}, false
+}
+
 // AddProject adds a new Project to the Database
-func AddProject(name string, path string) error {
+func AddProject(name string, path string, categories []string) error {
 	if _, ok := database.Projects[name]; ok {
 		return fmt.Errorf("project exists")
 	}
 
-	database.Projects[name] = Project{Name: name, Path: path}
+	database.Projects[name] = Project{Name: name, Path: path, Categories: categories, Created: time.Now()}
 
 	return nil
 }
@@ -198,23 +462,81 @@ func GetProjects() []Project {
 	return projects
 }
 
-// ListProjects returns a string representation of all the projects in the Database
-func ListProjects() string {
-	retval := ""
-
-	projects := GetProjects()
+// GetProjectList returns the projects in the Database sorted by path, archived projects are only included if includeArchived is set
+func GetProjectList(includeArchived bool) []Project {
+	var projects []Project
+	for _, p := range GetProjects() {
+		if includeArchived || !p.Archived {
+			projects = append(projects, p)
+		}
+	}
 
 	sort.Slice(projects, func(a int, b int) bool {
 		return projects[a].Path < projects[b].Path
 	})
+	return projects
+}
 
+// ListProjects returns a string representation of the projects in the Database, archived projects are only included if includeArchived is set
+func ListProjects(includeArchived bool) string {
+	return FormatProjects(GetProjectList(includeArchived))
+}
+
+// FormatProjects returns a string representation of projects
+func FormatProjects(projects []Project) string {
+	retval := ""
 	for _, v := range projects {
+		if v.Archived {
+			retval = fmt.Sprintf("%s%s: %s (archived)\n", retval, v.Name, v.Path)
+			continue
+		}
 		retval = fmt.Sprintf("%s%s: %s\n", retval, v.Name, v.Path)
 	}
 
 	return retval
 }
 
+// SavedQuery is a named project query
+type SavedQuery struct {
+	Name  string
+	Query string
+}
+
+// SaveQuery stores query under name, replacing an existing query with that name
+func SaveQuery(name string, query string) {
+	if database.Queries == nil {
+		database.Queries = make(map[string]string)
+	}
+	database.Queries[name] = query
+}
+
+// GetQuery returns the saved query with the given name
+func GetQuery(name string) (string, bool) {
+	query, ok := database.Queries[name]
+	return query, ok
+}
+
+// GetQueries returns all saved queries sorted by name
+func GetQueries() []SavedQuery {
+	var queries []SavedQuery
+	for name, query := range database.Queries {
+		queries = append(queries, SavedQuery{Name: name, Query: query})
+	}
+	sort.Slice(queries, func(a int, b int) bool {
+		return queries[a].Name < queries[b].Name
+	})
+	return queries
+}
+
+// DeleteQuery removes the saved query with the given name
+func DeleteQuery(name string) error {
+	if _, ok := database.Queries[name]; !ok {
+		return fmt.Errorf("query does not exist")
+	}
+	delete(database.Queries, name)
+	return nil
+}
+
 // GetProjectDir returns the path of a project identified by name
 func GetProjectDir(name string) (string, error) {
 	if _, ok := database.Projects[name]; !ok {
@@ -223,6 +545,143 @@ func GetProjectDir(name string) (string, error) {
 	return database.Projects[name].Path, nil
 }
 
+// GetProject returns the project identified by name
+func GetProject(name string) (Project, error) {
+	p, ok := database.Projects[name]
+	if !ok {
+		return p, fmt.Errorf("project does not exists")
+	}
+	return p, nil
+}
+
+// SetProjectArchived marks the project identified by name as archived or not
+func SetProjectArchived(name string, archived bool) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Archived = archived
+	database.Projects[name] = p
+	return nil
+}
+
+// SetProjectPath changes the path of the project identified by name
+func SetProjectPath(name string, path string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Path = path
+	database.Projects[name] = p
+	return nil
+}
+
+// RenameProject changes the name of the project identified by name to newName
+func RenameProject(name string, newName string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	if _, ok := database.Projects[newName]; ok {
+		return fmt.Errorf("project '%s' exists", newName)
+	}
+	delete(database.Projects, name)
+	p.Name = newName
+	database.Projects[newName] = p
+	return nil
+}
+
+// SetProjectCategories changes the categories of the project identified by name
+func SetProjectCategories(name string, categories []string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Categories = categories
+	database.Projects[name] = p
+	return nil
+}
+
+// SetProjectTags replaces the tags of the project identified by name
+func SetProjectTags(name string, tags []string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Tags = tags
+	database.Projects[name] = p
+	return nil
+}
+
+// SetProjectRemovedTags replaces the tags the user removed from the project
+// identified by name
+func SetProjectRemovedTags(name string, tags []string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.RemovedTags = tags
+	database.Projects[name] = p
+	return nil
+}
+
+// TouchProject records a visit of the project identified by name
+func TouchProject(name string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Accessed = time.Now()
+	p.AccessCount++
+	database.Projects[name] = p
+	return nil
+}
+
+// SetProjectFingerprint stores the fingerprint of the project identified by name
+func SetProjectFingerprint(name string, fingerprint Fingerprint) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Fingerprint = &fingerprint
+	database.Projects[name] = p
+	return nil
+}
+
+// SetProjectDetection stores the classification of the project identified by name
+func SetProjectDetection(name string, detection Detection) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Detection = &detection
+	database.Projects[name] = p
+	return nil
+}
+
+// SetProjectHooksAllowed stores the hash of the manifest whose hooks may run
+// for the project identified by name, an empty hash disallows them
+func SetProjectHooksAllowed(name string, hash string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.HooksAllowed = hash
+	database.Projects[name] = p
+	return nil
+}
+
+// SetProjectDescription sets the description of the project identified by name
+func SetProjectDescription(name string, description string) error {
+	p, ok := database.Projects[name]
+	if !ok {
+		return fmt.Errorf("project does not exists")
+	}
+	p.Description = description
+	database.Projects[name] = p
+	return nil
+}
+
 // DeleteProject deletes a project from the Database
 func DeleteProject(name string) {
 	delete(database.Projects, name)
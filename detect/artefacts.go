@@ -0,0 +1,156 @@
+package detect
+
+import (
+	"github.com/Tebro/prj/fsutil"
+	"github.com/Tebro/prj/gitignore"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// artefactDirs are the directories build systems write dependencies, caches
+// and build output to, relative to the project root. Entries starting with
+// **/ are matched at any depth.
+var artefactDirs = map[string][]string{
+	"npm":        {"node_modules", "dist", ".next", ".nuxt", ".parcel-cache", ".turbo"},
+	"yarn":       {"node_modules", "dist", ".next", ".nuxt", ".parcel-cache", ".turbo", ".yarn/cache"},
+	"pnpm":       {"node_modules", "dist", ".next", ".nuxt", ".parcel-cache", ".turbo"},
+	"cargo":      {"target"},
+	"maven":      {"target"},
+	"gradle":     {"build", ".gradle"},
+	"sbt":        {"target", "project/target"},
+	"pyproject":  {"build", "dist", ".tox", ".nox", ".pytest_cache", ".mypy_cache", ".ruff_cache", "**/__pycache__"},
+	"setuptools": {"build", "dist", ".tox", ".nox", ".pytest_cache", ".mypy_cache", ".ruff_cache", "**/__pycache__"},
+	"pipenv":     {".pytest_cache", ".mypy_cache", ".ruff_cache", "**/__pycache__"},
+	"pip":        {".pytest_cache", ".mypy_cache", ".ruff_cache", "**/__pycache__"},
+	"bundler":    {"vendor/bundle", ".bundle"},
+	"composer":   {"vendor"},
+	"mix":        {"_build", "deps"},
+	"rebar":      {"_build"},
+	"stack":      {".stack-work"},
+	"cabal":      {"dist-newstyle"},
+	"dune":       {"_build"},
+	"zig":        {"zig-cache", ".zig-cache", "zig-out"},
+	"pub":        {".dart_tool", "build"},
+	"swiftpm":    {".build"},
+	"dotnet":     {"**/bin", "**/obj"},
+	"cmake":      {"build"},
+	"meson":      {"build", "builddir"},
+}
+
+// Artefact is an artefact directory found in a project.
+type Artefact struct {
+	// Path is slash separated and relative to the project directory.
+	Path string
+	// Ignored is set if git ignores the directory. In projects without .git
+	// or .gitignore nothing can be checked and it is never set, the
+	// directory might just as well hold the only copy of something.
+	Ignored bool
+}
+
+// Artefacts returns the existing artefact directories of buildSystems in the
+// project in dir, sorted by path.
+//
+// Projects with a .gitignore may have a directory of the same name that is
+// part of the sources, so in those only ignored directories are returned.
+func Artefacts(dir string, buildSystems []string) []Artefact {
+	var patterns []string
+	for _, build := range buildSystems {
+		patterns = append(patterns, artefactDirs[build]...)
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	ignore := newIgnoreChecker(dir)
+	found := make(map[string]bool)
+	add := func(rel string) {
+		if ignore.matcher == nil || ignore.ignored(rel) {
+			found[rel] = ignore.matcher != nil
+		}
+	}
+
+	var anywhere []string
+	for _, pattern := range patterns {
+		if strings.HasPrefix(pattern, "**/") {
+			anywhere = append(anywhere, strings.TrimPrefix(pattern, "**/"))
+		} else if isDir(filepath.Join(dir, filepath.FromSlash(pattern))) {
+			add(pattern)
+		}
+	}
+
+	if len(anywhere) > 0 {
+		filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+			if err != nil || !info.IsDir() || p == dir {
+				return nil
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+			if _, ok := found[rel]; ok || fsutil.IsVCSMetadata(info.Name()) || skipDirs[info.Name()] || strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			for _, name := range anywhere {
+				if info.Name() == name {
+					add(rel)
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		})
+	}
+
+	var artefacts []Artefact
+	for rel, ignored := range found {
+		artefacts = append(artefacts, Artefact{Path: rel, Ignored: ignored})
+	}
+	sort.Slice(artefacts, func(a int, b int) bool {
+		return artefacts[a].Path < artefacts[b].Path
+	})
+	return artefacts
+}
+
+func isDir(p string) bool {
+	stat, err := os.Lstat(p)
+	return err == nil && stat.IsDir()
+}
+
+// ignoreChecker reports whether paths in a project are ignored by git. In
+// projects without a .gitignore or .git there is nothing to check against and
+// matcher is nil.
+type ignoreChecker struct {
+	root    string
+	matcher *gitignore.Matcher
+	loaded  map[string]bool
+}
+
+func newIgnoreChecker(root string) *ignoreChecker {
+	_, err := os.Stat(filepath.Join(root, ".gitignore"))
+	if err != nil && !isDir(filepath.Join(root, ".git")) {
+		return &ignoreChecker{root: root}
+	}
+	matcher := gitignore.New()
+	matcher.AddFile("", filepath.Join(root, ".git", "info", "exclude"))
+	matcher.AddDir("", root)
+	return &ignoreChecker{root: root, matcher: matcher, loaded: map[string]bool{"": true}}
+}
+
+// ignored reports whether the directory rel or one of its parents is ignored.
+func (c *ignoreChecker) ignored(rel string) bool {
+	segments := strings.Split(rel, "/")
+	for i := range segments {
+		base := path.Join(segments[:i]...)
+		if !c.loaded[base] {
+			c.matcher.AddDir(base, filepath.Join(c.root, filepath.FromSlash(base)))
+			c.loaded[base] = true
+		}
+		if c.matcher.Match(path.Join(segments[:i+1]...), true) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+// Package detect classifies projects by their languages and build systems,
+// using the extensions of their source files and well known marker files such
+// as go.mod or package.json in the project root.
+package detect
+
+import (
+	"errors"
+	"github.com/Tebro/prj/fsutil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Result is what was detected for a project.
+type Result struct {
+	// Languages are ordered by their share of the source code, the primary
+	// language first.
+	Languages []string
+	// BuildSystems are ordered by the marker files they were found by.
+	BuildSystems []string
+}
+
+// marker is a file in the project root that identifies a build system and
+// possibly a language. pattern is matched with filepath.Match.
+type marker struct {
+	pattern  string
+	language string
+	build    string
+}
+
+var markers = []marker{
+	{"go.mod", "Go", "go"},
+	{"Gopkg.toml", "Go", "dep"},
+	{"Cargo.toml", "Rust", "cargo"},
+	{"package.json", "JavaScript", "npm"},
+	{"yarn.lock", "JavaScript", "yarn"},
+	{"pnpm-lock.yaml", "JavaScript", "pnpm"},
+	{"deno.json", "TypeScript", "deno"},
+	{"pom.xml", "Java", "maven"},
+	{"build.gradle", "Java", "gradle"},
+	{"build.gradle.kts", "Kotlin", "gradle"},
+	{"build.sbt", "Scala", "sbt"},
+	{"pyproject.toml", "Python", "pyproject"},
+	{"setup.py", "Python", "setuptools"},
+	{"Pipfile", "Python", "pipenv"},
+	{"requirements.txt", "Python", "pip"},
+	{"Gemfile", "Ruby", "bundler"},
+	{"composer.json", "PHP", "composer"},
+	{"mix.exs", "Elixir", "mix"},
+	{"rebar.config", "Erlang", "rebar"},
+	{"stack.yaml", "Haskell", "stack"},
+	{"*.cabal", "Haskell", "cabal"},
+	{"dune-project", "OCaml", "dune"},
+	{"build.zig", "Zig", "zig"},
+	{"pubspec.yaml", "Dart", "pub"},
+	{"Package.swift", "Swift", "swiftpm"},
+	{"*.sln", "C#", "dotnet"},
+	{"*.csproj", "C#", "dotnet"},
+	{"*.fsproj", "F#", "dotnet"},
+	{"CMakeLists.txt", "", "cmake"},
+	{"meson.build", "", "meson"},
+	{"MODULE.bazel", "", "bazel"},
+	{"WORKSPACE", "", "bazel"},
+	{"Makefile", "", "make"},
+	{"GNUmakefile", "", "make"},
+	{"justfile", "", "just"},
+	{"flake.nix", "", "nix"},
+}
+
+var extensions = map[string]string{
+	".go":     "Go",
+	".rs":     "Rust",
+	".js":     "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".jsx":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hh":     "C++",
+	".hpp":    "C++",
+	".cs":     "C#",
+	".fs":     "F#",
+	".swift":  "Swift",
+	".m":      "Objective-C",
+	".php":    "PHP",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".hs":     "Haskell",
+	".ml":     "OCaml",
+	".clj":    "Clojure",
+	".lua":    "Lua",
+	".pl":     "Perl",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zig":    "Zig",
+	".dart":   "Dart",
+	".jl":     "Julia",
+	".nim":    "Nim",
+	".vue":    "Vue",
+	".svelte": "Svelte",
+}
+
+// skipDirs hold dependencies and build output rather than the project's own
+// code.
+var skipDirs = map[string]bool{
+	"node_modules":     true,
+	"bower_components": true,
+	"vendor":           true,
+	"target":           true,
+	"build":            true,
+	"dist":             true,
+	"venv":             true,
+	"__pycache__":      true,
+	"_build":           true,
+	"deps":             true,
+}
+
+// minShare is the share of the source code a language needs to be listed.
+const minShare = 0.1
+
+// maxFiles bounds the number of files looked at in very large trees.
+const maxFiles = 20000
+
+// errTooMany stops the walk once maxFiles files have been seen.
+var errTooMany = errors.New("too many files")
+
+// Detect classifies the project in dir.
+func Detect(dir string) (Result, error) {
+	var result Result
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return result, err
+	}
+
+	var markerLanguages []string
+	for _, m := range markers {
+		for _, e := range entries {
+			if matched, _ := filepath.Match(m.pattern, e.Name()); !matched || e.IsDir() {
+				continue
+			}
+			result.BuildSystems = appendNew(result.BuildSystems, m.build)
+			if m.language != "" {
+				markerLanguages = appendNew(markerLanguages, m.language)
+			}
+			break
+		}
+	}
+
+	sizes, total := sourceSizes(dir)
+	var languages []string
+	for name, size := range sizes {
+		if float64(size) >= minShare*float64(total) {
+			languages = append(languages, name)
+		}
+	}
+	sort.Slice(languages, func(a int, b int) bool {
+		if sizes[languages[a]] != sizes[languages[b]] {
+			return sizes[languages[a]] > sizes[languages[b]]
+		}
+		return languages[a] < languages[b]
+	})
+
+	// A marker names the language of the project even if it's a small part
+	// of the code, but package.json alone doesn't make a TypeScript project
+	// a JavaScript one.
+	for _, name := range markerLanguages {
+		if sizes[name] > 0 || total == 0 {
+			languages = appendNew(languages, name)
+		}
+	}
+	result.Languages = languages
+	return result, nil
+}
+
+// sourceSizes returns the number of bytes of source code per language in the
+// tree below dir, and their total.
+func sourceSizes(dir string) (map[string]int64, int64) {
+	sizes := make(map[string]int64)
+	var total int64
+	files := 0
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if path != dir && (skipDirs[name] || fsutil.IsVCSMetadata(name) || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if files++; files > maxFiles {
+			return errTooMany
+		}
+		if language, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok && info.Mode().IsRegular() {
+			sizes[language] += info.Size()
+			total += info.Size()
+		}
+		return nil
+	})
+	return sizes, total
+}
+
+func appendNew(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
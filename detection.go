@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/detect"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// detectionMaxAge is how long the cached languages and build systems of a
+// project are used before the project is classified again.
+const detectionMaxAge = 7 * 24 * time.Hour
+
+// detectionStale reports whether p has to be classified again. Besides age,
+// adding or removing files in the project root, like a go.mod, changes its
+// modification time and makes the classification stale.
+func detectionStale(p db.Project, now time.Time) bool {
+	if p.Detection == nil || now.Sub(p.Detection.Refreshed) > detectionMaxAge {
+		return true
+	}
+	info, err := os.Stat(p.Path)
+	return err == nil && info.ModTime().After(p.Detection.Refreshed)
+}
+
+// autoTags returns the tags the AutoTag rules give to a project with the
+// detected languages and build systems. Rules match case insensitively.
+func autoTags(d db.Detection) []string {
+	var tags []string
+	for name, ruleTags := range db.GetConfigAutoTags() {
+		for _, detected := range append(append([]string{}, d.Languages...), d.BuildSystems...) {
+			if strings.EqualFold(name, detected) {
+				tags = append(tags, ruleTags...)
+				break
+			}
+		}
+	}
+	return tags
+}
+
+// detectProjects classifies the existing projects among projects, all of them
+// if force is set and otherwise only those without a fresh classification.
+// The results are stored together with the tags from the AutoTag rules,
+// except those the user removed, and the updated projects are returned in the
+// same order.
+func detectProjects(projects []db.Project, force bool) []db.Project {
+	now := time.Now()
+	results := make([]*db.Detection, len(projects))
+	sem := make(chan struct{}, selectorWorkers)
+	var wg sync.WaitGroup
+	for i, p := range projects {
+		if !force && !detectionStale(p, now) {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p db.Project) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if isDir, _ := pathIsDir(p.Path); !isDir {
+				return
+			}
+			if detected, err := detect.Detect(p.Path); err == nil {
+				results[i] = &db.Detection{
+					Languages:    detected.Languages,
+					BuildSystems: detected.BuildSystems,
+					Refreshed:    now,
+				}
+			}
+		}(i, p)
+	}
+	wg.Wait()
+
+	updated := make([]db.Project, len(projects))
+	for i, p := range projects {
+		if d := results[i]; d != nil {
+			db.SetProjectDetection(p.Name, *d)
+			if tags := autoTags(*d); len(tags) > 0 {
+				db.SetProjectTags(p.Name, mergeTags(p.Tags, tags, p.RemovedTags))
+			}
+			if stored, err := db.GetProject(p.Name); err == nil {
+				p = stored
+			}
+		}
+		updated[i] = p
+	}
+	return updated
+}
+
+// detectRegistered classifies projects right after they were registered, so
+// the AutoTag rules apply to them from the start. Projects registered before
+// detection existed are classified when a command needs it.
+func detectRegistered(names ...string) {
+	var projects []db.Project
+	for _, name := range names {
+		if p, err := db.GetProject(name); err == nil {
+			projects = append(projects, p)
+		}
+	}
+	detectProjects(projects, true)
+}
+
+func formatDetection(p db.Project) string {
+	if p.Detection == nil {
+		return "not classified"
+	}
+	languages := strings.Join(p.Detection.Languages, ", ")
+	if languages == "" {
+		languages = "no known languages"
+	}
+	if len(p.Detection.BuildSystems) == 0 {
+		return languages
+	}
+	return fmt.Sprintf("%s (%s)", languages, strings.Join(p.Detection.BuildSystems, ", "))
+}
+
+func detectCommand(c *cli.Context) error {
+	projects, err := selectTargets(c, db.GetProjectList(c.Bool("all")))
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return exitErrorWrapper("no projects selected")
+	}
+
+	for _, p := range detectProjects(projects, true) {
+		log(c, "%s: %s", p.Name, formatDetection(p))
+	}
+	return nil
+}
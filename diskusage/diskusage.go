@@ -0,0 +1,158 @@
+// Package diskusage measures the size of directory trees. The content of
+// every directory is cached together with the modification times of the
+// directory and the files in it and reused while those stay the same, so
+// measuring the same trees again only needs to stat them instead of reading
+// every directory.
+//
+// A directory's modification time changes when entries are added, removed or
+// renamed in it, a file's when it is written in place, e.g. a growing log.
+package diskusage
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Size is the size of a directory tree.
+type Size struct {
+	Bytes int64
+	Files int
+}
+
+// fileEntry is a cached regular file.
+type fileEntry struct {
+	Size int64
+	// ModTime is in nanoseconds since the epoch, which keeps the cache small.
+	ModTime int64
+}
+
+// dirEntry is the cached content of a single directory.
+type dirEntry struct {
+	ModTime time.Time
+	// Files are the regular files directly inside the directory by name.
+	Files map[string]fileEntry `json:",omitempty"`
+	// Dirs are the names of the subdirectories.
+	Dirs []string `json:",omitempty"`
+}
+
+// current reports whether the files of the cached directory e in dir are
+// unchanged. Its own modification time covers added and removed entries.
+func (e dirEntry) current(dir string) bool {
+	for name, f := range e.Files {
+		info, err := os.Lstat(filepath.Join(dir, name))
+		if err != nil || !info.Mode().IsRegular() || info.Size() != f.Size || info.ModTime().UnixNano() != f.ModTime {
+			return false
+		}
+	}
+	return true
+}
+
+// Cache holds the sizes of directories. It is safe for concurrent use.
+type Cache struct {
+	// Refresh reads every directory again instead of trusting the cache.
+	Refresh bool
+
+	path    string
+	mutex   sync.Mutex
+	dirs    map[string]dirEntry
+	visited map[string]bool
+	roots   []string
+}
+
+// Load reads the cache stored at path. A missing or unreadable cache results
+// in an empty one.
+func Load(path string) *Cache {
+	c := &Cache{path: path, dirs: make(map[string]dirEntry), visited: make(map[string]bool)}
+	if data, err := ioutil.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &c.dirs); err != nil || c.dirs == nil {
+			c.dirs = make(map[string]dirEntry)
+		}
+	}
+	return c
+}
+
+// Save writes the cache back to where it was loaded from. Directories below
+// the measured trees that no longer exist are dropped.
+func (c *Cache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for dir := range c.dirs {
+		if !c.visited[dir] && c.below(dir) {
+			delete(c.dirs, dir)
+		}
+	}
+
+	data, err := json.Marshal(c.dirs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0644)
+}
+
+// below reports whether dir is inside one of the measured trees.
+func (c *Cache) below(dir string) bool {
+	for _, root := range c.roots {
+		if dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Measure returns the size of the tree below dir. Symlinks are not followed
+// and a missing dir has size zero.
+func (c *Cache) Measure(dir string) Size {
+	dir = filepath.Clean(dir)
+	c.mutex.Lock()
+	c.roots = append(c.roots, dir)
+	c.mutex.Unlock()
+	return c.measure(dir)
+}
+
+func (c *Cache) measure(dir string) Size {
+	stat, err := os.Lstat(dir)
+	if err != nil || !stat.IsDir() {
+		return Size{}
+	}
+
+	c.mutex.Lock()
+	entry, ok := c.dirs[dir]
+	c.visited[dir] = true
+	c.mutex.Unlock()
+
+	if c.Refresh || !ok || !entry.ModTime.Equal(stat.ModTime()) || !entry.current(dir) {
+		entry = dirEntry{ModTime: stat.ModTime(), Files: make(map[string]fileEntry)}
+		infos, _ := ioutil.ReadDir(dir)
+		for _, info := range infos {
+			switch {
+			case info.IsDir():
+				entry.Dirs = append(entry.Dirs, info.Name())
+			case info.Mode().IsRegular():
+				entry.Files[info.Name()] = fileEntry{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+			}
+		}
+		c.mutex.Lock()
+		c.dirs[dir] = entry
+		c.mutex.Unlock()
+	}
+
+	size := Size{Files: len(entry.Files)}
+	for _, f := range entry.Files {
+		size.Bytes += f.Size
+	}
+	for _, name := range entry.Dirs {
+		sub := c.measure(filepath.Join(dir, name))
+		size.Bytes += sub.Bytes
+		size.Files += sub.Files
+	}
+	return size
+}
@@ -0,0 +1,82 @@
+package diskusage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func write(t *testing.T, path string, size int) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "project")
+	write(t, filepath.Join(root, "a"), 10)
+	write(t, filepath.Join(root, "sub", "b"), 20)
+	write(t, filepath.Join(root, "sub", "deep", "c"), 30)
+	if err := os.Symlink("a", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+	cachePath := filepath.Join(dir, "cache", "sizes.json")
+
+	c := Load(cachePath)
+	if got, want := c.Measure(root), (Size{Bytes: 60, Files: 3}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A file written in place doesn't change the modification time of its
+	// directory.
+	logFile := filepath.Join(root, "sub", "deep", "c")
+	deep := filepath.Dir(logFile)
+	stat, err := os.Stat(deep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, logFile, 300)
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(logFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(deep, stat.ModTime(), stat.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Load(cachePath).Measure(root), (Size{Bytes: 330, Files: 3}); got != want {
+		t.Errorf("after writing in place got %+v, want %+v", got, want)
+	}
+
+	if err := os.RemoveAll(filepath.Join(root, "sub")); err != nil {
+		t.Fatal(err)
+	}
+	write(t, filepath.Join(root, "new"), 5)
+	if got, want := Load(cachePath).Measure(root), (Size{Bytes: 15, Files: 2}); got != want {
+		t.Errorf("after removing and adding got %+v, want %+v", got, want)
+	}
+
+	if got := c.Measure(filepath.Join(dir, "missing")); got != (Size{}) {
+		t.Errorf("missing directory has size %+v", got)
+	}
+}
+
+func TestLoadInvalidCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sizes.json")
+	write(t, path, 0)
+	if err := ioutil.WriteFile(path, []byte(`{"/x": {"Files": 3}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if c := Load(path); len(c.dirs) != 0 {
+		t.Errorf("an unreadable cache was used: %+v", c.dirs)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/license"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// getAuthor returns the copyright holder for license texts, which is the
+// Author option or else the git user name.
+func getAuthor() (string, error) {
+	if author := db.GetConfigAuthor(); author != "" {
+		return author, nil
+	}
+
+	out, err := exec.Command("git", "config", "--get", "user.name").Output()
+	if err == nil && len(strings.TrimSpace(string(out))) > 0 {
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	return "", fmt.Errorf("no author configured, set one with 'prj config set Author \"Your Name\"'")
+}
+
+// writeLicense writes the LICENSE file of the license with the given id to
+// dir. An existing LICENSE is only replaced if force is set.
+func writeLicense(dir string, l license.License, force bool) error {
+	filename := filepath.Join(dir, "LICENSE")
+	exists, err := pathExists(filename)
+	if err != nil {
+		return err
+	}
+	if exists && !force {
+		return fmt.Errorf("%s exists, use --force to replace it", filename)
+	}
+
+	author, err := getAuthor()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filename, []byte(l.Render(author, time.Now().Year())), 0644)
+}
+
+// writeReadme generates a README.md skeleton in dir unless it already has a
+// README. It returns whether a file was written.
+func writeReadme(dir string, name string, description string, l *license.License) (bool, error) {
+	existing, err := filepath.Glob(filepath.Join(dir, "README*"))
+	if err != nil {
+		return false, err
+	}
+	if len(existing) > 0 {
+		return false, nil
+	}
+
+	if description == "" {
+		description = "TODO: describe the project."
+	}
+
+	readme := fmt.Sprintf("# %s\n\n%s\n\n## Getting started\n\nTODO\n", name, description)
+	if l != nil {
+		readme = fmt.Sprintf("%s\n## License\n\n%s, see [LICENSE](LICENSE).\n", readme, l.Name)
+	}
+
+	return true, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte(readme), 0644)
+}
+
+// getNewLicense returns the license requested with --license, or nil.
+func getNewLicense(c *cli.Context) (*license.License, error) {
+	if len(c.String("license")) == 0 {
+		return nil, nil
+	}
+
+	l, err := license.Get(c.String("license"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := getAuthor(); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func shouldCreateReadme(c *cli.Context) bool {
+	return c.Bool("readme") || len(c.String("description")) > 0
+}
+
+func setLicense(c *cli.Context) error {
+	if c.NArg() == 0 {
+		if c.String("format") != "" {
+			type licenseRecord struct {
+				ID   string
+				Name string
+			}
+			var records []licenseRecord
+			for _, l := range license.All() {
+				records = append(records, licenseRecord{ID: l.ID, Name: l.Name})
+			}
+			return writeFormatted(c, records)
+		}
+
+		retval := ""
+		for _, l := range license.All() {
+			retval = fmt.Sprintf("%s%s: %s\n", retval, l.ID, l.Name)
+		}
+		log(c, `Licenses
+--------
+%s`, retval)
+		return nil
+	}
+
+	if c.NArg() != 2 {
+		return exitErrorWrapper("invalid number of arguments, expected 2")
+	}
+
+	path, err := db.GetProjectDir(c.Args()[0])
+	if err != nil {
+		return exitErrorWrapper("could not add license: %s", err.Error())
+	}
+
+	l, err := license.Get(c.Args()[1])
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+
+	if err := writeLicense(path, l, c.Bool("force")); err != nil {
+		return exitErrorWrapper("could not add license: %s", err.Error())
+	}
+
+	log(c, "Added %s to %s", l.Name, filepath.Join(path, "LICENSE"))
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/scan"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	problemMissing    = "missing"
+	problemNotDir     = "not a directory"
+	problemDuplicate  = "duplicate"
+	problemNested     = "nested"
+	problemOutside    = "outside base dirs"
+	problemPermission = "permission"
+)
+
+// problem is something wrong with a registered project.
+type problem struct {
+	kind    string
+	project db.Project
+	detail  string
+}
+
+func (p problem) String() string {
+	return fmt.Sprintf("%s: %s (%s): %s", p.kind, p.project.Name, p.project.Path, p.detail)
+}
+
+// broken reports whether the path of the project is unusable, only those
+// problems can be fixed by pruning or re-pointing.
+func (p problem) broken() bool {
+	return p.kind == problemMissing || p.kind == problemNotDir
+}
+
+func isBelow(path string, dir string) bool {
+	return path != dir && strings.HasPrefix(path, strings.TrimSuffix(dir, string(os.PathSeparator))+string(os.PathSeparator))
+}
+
+func checkAccess(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	_, err = f.Readdirnames(1)
+	f.Close()
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return checkWritable(path)
+}
+
+// diagnose checks all registered projects and returns the problems found,
+// sorted by project name.
+func diagnose(c *cli.Context) []problem {
+	projects := db.GetProjects()
+	sort.Slice(projects, func(a int, b int) bool {
+		return projects[a].Name < projects[b].Name
+	})
+
+	var baseDirs []string
+	for _, dir := range getBaseDirs(c) {
+		baseDirs = append(baseDirs, absPath(dir))
+	}
+
+	var problems []problem
+	byPath := make(map[string][]string)
+	var usable []db.Project
+	for _, p := range projects {
+		path := absPath(p.Path)
+		byPath[path] = append(byPath[path], p.Name)
+
+		stat, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			problems = append(problems, problem{problemMissing, p, "path does not exist"})
+			continue
+		}
+		if err != nil {
+			problems = append(problems, problem{problemPermission, p, err.Error()})
+			continue
+		}
+		if !stat.IsDir() {
+			problems = append(problems, problem{problemNotDir, p, "path is a file"})
+			continue
+		}
+		if err := checkAccess(path); err != nil {
+			problems = append(problems, problem{problemPermission, p, err.Error()})
+		}
+		usable = append(usable, p)
+	}
+
+	for _, p := range projects {
+		if names := byPath[absPath(p.Path)]; len(names) > 1 {
+			problems = append(problems, problem{problemDuplicate, p, "path is also registered as " + strings.Join(without(names, p.Name), ", ")})
+		}
+	}
+
+	for _, p := range usable {
+		path := absPath(p.Path)
+		for _, parent := range usable {
+			if isBelow(path, absPath(parent.Path)) {
+				problems = append(problems, problem{problemNested, p, "inside project " + parent.Name})
+			}
+		}
+
+		inside := false
+		for _, dir := range baseDirs {
+			if path == dir || isBelow(path, dir) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			problems = append(problems, problem{problemOutside, p, "not below " + strings.Join(baseDirs, ", ")})
+		}
+	}
+
+	sort.SliceStable(problems, func(a int, b int) bool {
+		return problems[a].project.Name < problems[b].project.Name
+	})
+	return problems
+}
+
+func without(list []string, s string) []string {
+	var out []string
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// findMoved searches the base dirs for unregistered directories with the same
+// name as the missing projects, keyed by project name.
+func findMoved(c *cli.Context, problems []problem) map[string][]string {
+	wanted := make(map[string][]string)
+	for _, p := range problems {
+		if p.kind == problemMissing {
+			base := filepath.Base(p.project.Path)
+			wanted[base] = append(wanted[base], p.project.Name)
+		}
+	}
+	found := make(map[string][]string)
+	if len(wanted) == 0 {
+		return found
+	}
+
+	registered := make(map[string]bool)
+	for _, p := range db.GetProjects() {
+		registered[absPath(p.Path)] = true
+	}
+
+	opts := scan.Options{MaxDepth: c.Int("depth"), Ignore: scan.DefaultIgnore}
+	dirs := scan.Find(getBaseDirs(c), opts, func(dir string) bool {
+		_, ok := wanted[filepath.Base(dir)]
+		return ok && !registered[dir]
+	})
+	for _, dir := range dirs {
+		for _, name := range wanted[filepath.Base(dir)] {
+			found[name] = append(found[name], dir)
+		}
+	}
+	return found
+}
+
+func prompt(c *cli.Context, in *bufio.Reader, question string) string {
+	fmt.Fprintf(c.App.Writer, "%s ", question)
+	answer, _ := in.ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+// fixInteractive asks what to do about a broken project, it returns true if
+// the problem was fixed.
+func fixInteractive(c *cli.Context, in *bufio.Reader, p problem, candidates []string) bool {
+	log(c, "%s", p)
+	for i, dir := range candidates {
+		log(c, "  [%d] %s", i+1, dir)
+	}
+
+	question := "[p]rune, [e]nter new path or [s]kip?"
+	if len(candidates) > 0 {
+		question = "[p]rune, re-point to [1-" + fmt.Sprint(len(candidates)) + "], [e]nter new path or [s]kip?"
+	}
+
+	for {
+		answer := strings.ToLower(prompt(c, in, question))
+		switch answer {
+		case "p", "prune":
+			db.DeleteProject(p.project.Name)
+			log(c, "Removed %s", p.project.Name)
+			return true
+		case "e", "enter":
+			path := absPath(prompt(c, in, "New path:"))
+			if isDir, err := pathIsDir(path); err != nil || !isDir {
+				log(c, "'%s' is not a directory", path)
+				continue
+			}
+			updateProjectPath(c, p.project.Name, path)
+			log(c, "Re-registered %s at %s", p.project.Name, path)
+			return true
+		case "", "s", "skip":
+			return false
+		}
+		for i, dir := range candidates {
+			if answer == fmt.Sprint(i+1) {
+				updateProjectPath(c, p.project.Name, dir)
+				log(c, "Re-pointed %s to %s", p.project.Name, dir)
+				return true
+			}
+		}
+	}
+}
+
+func runDoctor(c *cli.Context) error {
+	fix := c.String("fix")
+	switch fix {
+	case "", "prune", "repoint", "interactive":
+	default:
+		return exitErrorWrapper("unknown fix '%s', expected prune, repoint or interactive", fix)
+	}
+	if fix == "interactive" && !stdinIsTerminal() {
+		return exitErrorWrapper("--fix interactive needs a terminal")
+	}
+
+	problems := diagnose(c)
+	if len(problems) == 0 {
+		log(c, "No problems found")
+		return nil
+	}
+
+	var moved map[string][]string
+	if fix == "repoint" || fix == "interactive" {
+		moved = findMoved(c, problems)
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	var remaining []problem
+	for _, p := range problems {
+		fixed := false
+		switch {
+		case fix == "prune" && p.broken():
+			db.DeleteProject(p.project.Name)
+			log(c, "Removed %s (%s)", p.project.Name, p.kind)
+			fixed = true
+		case fix == "repoint" && p.kind == problemMissing:
+			// A directory with the same name can just as well be another
+			// project, so the user has to confirm it.
+			candidates := moved[p.project.Name]
+			switch {
+			case len(candidates) > 1:
+				p.detail += ", found several candidates: " + strings.Join(candidates, ", ")
+			case len(candidates) == 1 && !stdinIsTerminal():
+				p.detail += ", found " + candidates[0] + ", run 'prj doctor --fix repoint' in a terminal to confirm it"
+			case len(candidates) == 1:
+				answer := strings.ToLower(prompt(c, in, fmt.Sprintf("%s: %s has the same name, it may be a different directory. Re-point? [y/N]", p.project.Name, candidates[0])))
+				if answer == "y" || answer == "yes" {
+					updateProjectPath(c, p.project.Name, candidates[0])
+					log(c, "Re-pointed %s to %s", p.project.Name, candidates[0])
+					fixed = true
+				}
+			}
+		case fix == "interactive" && p.broken():
+			fixed = fixInteractive(c, in, p, moved[p.project.Name])
+		}
+		if !fixed {
+			remaining = append(remaining, p)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return nil
+	}
+
+	report := ""
+	for _, p := range remaining {
+		report += p.String() + "\n"
+	}
+	log(c, `Problems
+--------
+%s`, report)
+
+	// Fixes that were applied are kept even though problems remain.
+	db.PrepareForShutdown()
+	return exitErrorWrapper("%d problems found", len(remaining))
+}
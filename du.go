@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/diskusage"
+	"github.com/Tebro/prj/fsutil"
+	"github.com/Tebro/prj/selector"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// duSortKeys lists the keys du can sort by.
+var duSortKeys = []string{"size", "artefacts", "name", "path"}
+
+// duRecord is the disk usage of a project. Size covers everything in the
+// project directory, WithoutVCS leaves out the version control metadata and
+// Artefacts is what 'prj clean' would free.
+type duRecord struct {
+	Name       string
+	Path       string
+	Categories []string
+	Size       int64
+	Files      int
+	WithoutVCS int64
+	Artefacts  int64
+}
+
+// duCategoryRecord is the disk usage of all projects in a category.
+type duCategoryRecord struct {
+	Category   string
+	Projects   int
+	Size       int64
+	Files      int
+	WithoutVCS int64
+	Artefacts  int64
+}
+
+func sizeCachePath() string {
+	return filepath.Join(db.GetCacheDir(), "du.json")
+}
+
+// measureProject returns the disk usage of p using cache.
+func measureProject(cache *diskusage.Cache, p db.Project, categories []string) duRecord {
+	total := cache.Measure(p.Path)
+	r := duRecord{
+		Name:       p.Name,
+		Path:       p.Path,
+		Categories: categories,
+		Size:       total.Bytes,
+		Files:      total.Files,
+		WithoutVCS: total.Bytes,
+	}
+	if r.Categories == nil {
+		r.Categories = []string{}
+	}
+	for _, meta := range fsutil.VCSDirs {
+		r.WithoutVCS -= cache.Measure(filepath.Join(p.Path, meta)).Bytes
+	}
+	// A project with an invalid manifest can't be cleaned, so nothing is
+	// reclaimable. Neither are the directories clean skips without --force.
+	artefacts, _ := projectArtefacts(p)
+	for _, a := range artefacts {
+		if a.Ignored {
+			r.Artefacts += cache.Measure(filepath.Join(p.Path, filepath.FromSlash(a.Path))).Bytes
+		}
+	}
+	return r
+}
+
+// usageLess orders by size or artefacts largest first. For the other keys
+// and ties the names are compared, so the output is stable.
+func usageLess(key string, aName string, aSize int64, aArtefacts int64, bName string, bSize int64, bArtefacts int64) bool {
+	switch {
+	case key == "size" && aSize != bSize:
+		return aSize > bSize
+	case key == "artefacts" && aArtefacts != bArtefacts:
+		return aArtefacts > bArtefacts
+	}
+	return aName < bName
+}
+
+func groupByCategory(records []duRecord) []duCategoryRecord {
+	categories := make(map[string]*duCategoryRecord)
+	var names []string
+	for _, r := range records {
+		name := strings.Join(r.Categories, "/")
+		total, ok := categories[name]
+		if !ok {
+			total = &duCategoryRecord{Category: name}
+			categories[name] = total
+			names = append(names, name)
+		}
+		total.Projects++
+		total.Size += r.Size
+		total.Files += r.Files
+		total.WithoutVCS += r.WithoutVCS
+		total.Artefacts += r.Artefacts
+	}
+
+	var out []duCategoryRecord
+	for _, name := range names {
+		out = append(out, *categories[name])
+	}
+	return out
+}
+
+func diskUsage(c *cli.Context) error {
+	key := c.String("sort")
+	switch key {
+	case "size", "artefacts", "name", "path":
+	default:
+		return exitErrorWrapper("unknown sort key '%s', expected one of %s", key, strings.Join(duSortKeys, ", "))
+	}
+
+	items, err := filterTargets(c, db.GetProjectList(c.Bool("all")))
+	if err != nil {
+		return err
+	}
+	// Missing projects take no space.
+	items = selector.Select(items, selector.Not(selector.Missing), selectorWorkers)
+	// The artefacts depend on the build systems.
+	projects := make([]db.Project, len(items))
+	for i, item := range items {
+		projects[i] = item.Project
+	}
+	for i, p := range detectProjects(projects, false) {
+		items[i].Project = p
+	}
+
+	cache := diskusage.Load(sizeCachePath())
+	cache.Refresh = c.Bool("refresh")
+	records := make([]duRecord, len(items))
+	sem := make(chan struct{}, selectorWorkers)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, p db.Project, categories []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			records[i] = measureProject(cache, p, categories)
+		}(i, item.Project, item.Categories)
+	}
+	wg.Wait()
+	if err := cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not save the size cache: %s\n", err.Error())
+	}
+
+	sort.SliceStable(records, func(a int, b int) bool {
+		x, y := records[a], records[b]
+		if c.Bool("reverse") {
+			x, y = y, x
+		}
+		if key == "path" {
+			return x.Path < y.Path
+		}
+		return usageLess(key, x.Name, x.Size, x.Artefacts, y.Name, y.Size, y.Artefacts)
+	})
+	// The categories are built from all projects, --limit applies to them.
+	if c.Bool("by-category") {
+		return printCategoryUsage(c, key, groupByCategory(records))
+	}
+	if limit := c.Int("limit"); limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	if c.String("format") != "" {
+		return writeFormatted(c, records)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SIZE\tWITHOUT VCS\tARTEFACTS\tPROJECT")
+	var total duRecord
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatSize(r.Size), formatSize(r.WithoutVCS), formatSize(r.Artefacts), r.Name)
+		total.Size += r.Size
+		total.WithoutVCS += r.WithoutVCS
+		total.Artefacts += r.Artefacts
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", formatSize(total.Size), formatSize(total.WithoutVCS), formatSize(total.Artefacts), "total")
+	return w.Flush()
+}
+
+func printCategoryUsage(c *cli.Context, key string, categories []duCategoryRecord) error {
+	sort.SliceStable(categories, func(a int, b int) bool {
+		x, y := categories[a], categories[b]
+		if c.Bool("reverse") {
+			x, y = y, x
+		}
+		return usageLess(key, x.Category, x.Size, x.Artefacts, y.Category, y.Size, y.Artefacts)
+	})
+	if limit := c.Int("limit"); limit > 0 && len(categories) > limit {
+		categories = categories[:limit]
+	}
+
+	if c.String("format") != "" {
+		return writeFormatted(c, categories)
+	}
+
+	w := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "SIZE\tWITHOUT VCS\tARTEFACTS\tPROJECTS\tCATEGORY")
+	var total duCategoryRecord
+	for _, r := range categories {
+		name := r.Category
+		if name == "" {
+			name = "(no category)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", formatSize(r.Size), formatSize(r.WithoutVCS), formatSize(r.Artefacts), r.Projects, name)
+		total.Size += r.Size
+		total.WithoutVCS += r.WithoutVCS
+		total.Artefacts += r.Artefacts
+		total.Projects += r.Projects
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", formatSize(total.Size), formatSize(total.WithoutVCS), formatSize(total.Artefacts), total.Projects, "total")
+	return w.Flush()
+}
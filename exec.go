@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+func countDashes(args []string) int {
+	count := 0
+	for _, arg := range args {
+		if arg == "--" {
+			count++
+		}
+	}
+	return count
+}
+
+// splitExecArgs splits the arguments of exec at -- into the query and the
+// command. The flag parser drops a -- that directly follows the flags, in
+// that case there is no query and all arguments are the command.
+func splitExecArgs(c *cli.Context) ([]string, []string) {
+	args := []string(c.Args())
+	if countDashes(os.Args[1:]) > countDashes(args) {
+		return nil, args
+	}
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return nil, args
+}
+
+// prefixWriter writes every complete line to out with prefix in front of it.
+// Writers sharing a mutex never mix their lines.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	mutex  *sync.Mutex
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.mutex.Lock()
+		fmt.Fprintf(w.out, "%s%s", w.prefix, w.buf[:i+1])
+		w.mutex.Unlock()
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// flush writes a last line that doesn't end with a newline.
+func (w *prefixWriter) flush() {
+	if len(w.buf) > 0 {
+		w.Write([]byte("\n"))
+	}
+}
+
+// execResult is the outcome of running the command in one project.
+type execResult struct {
+	name    string
+	err     error
+	skipped bool
+}
+
+// execRunner runs a command in the directories of projects.
+type execRunner struct {
+	c       *cli.Context
+	command []string
+	group   bool
+	width   int
+	mutex   sync.Mutex
+}
+
+func (r *execRunner) run(p db.Project, stdin io.Reader) error {
+	if isDir, _ := pathIsDir(p.Path); !isDir {
+		return fmt.Errorf("project directory is missing")
+	}
+
+	cmd := exec.Command(r.command[0], r.command[1:]...)
+	cmd.Dir = p.Path
+	cmd.Stdin = stdin
+	cmd.Env = append(os.Environ(), "PRJ_NAME="+p.Name, "PRJ_PATH="+p.Path)
+
+	if r.group {
+		// Output is collected and written in one piece once the command is
+		// done, stdout and stderr interleaved as they were written.
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		err := cmd.Run()
+		r.mutex.Lock()
+		fmt.Fprintf(r.c.App.Writer, "==> %s <==\n%s", p.Name, output.String())
+		if output.Len() > 0 && !bytes.HasSuffix(output.Bytes(), []byte("\n")) {
+			fmt.Fprintln(r.c.App.Writer)
+		}
+		r.mutex.Unlock()
+		return err
+	}
+
+	prefix := fmt.Sprintf("%-*s | ", r.width, p.Name)
+	stdout := &prefixWriter{out: r.c.App.Writer, prefix: prefix, mutex: &r.mutex}
+	stderr := &prefixWriter{out: os.Stderr, prefix: prefix, mutex: &r.mutex}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	stdout.flush()
+	stderr.flush()
+	return err
+}
+
+func execCommand(c *cli.Context) error {
+	_, command := splitExecArgs(c)
+	if len(command) == 0 {
+		return exitErrorWrapper("no command given, expected prj exec [query] -- <command>")
+	}
+	projects, err := selectTargets(c, db.GetProjectList(c.Bool("all")))
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return exitErrorWrapper("no projects selected")
+	}
+
+	parallel := c.Int("parallel")
+	if parallel < 1 {
+		parallel = 1
+	}
+	runner := &execRunner{c: c, command: command, group: c.Bool("group")}
+	for _, p := range projects {
+		if len(p.Name) > runner.width {
+			runner.width = len(p.Name)
+		}
+	}
+	// Only a command running on its own can be interactive.
+	var stdin io.Reader
+	if parallel == 1 {
+		stdin = os.Stdin
+	}
+
+	results := make([]execResult, len(projects))
+	var failedMutex sync.Mutex
+	failed := false
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				p := projects[i]
+				failedMutex.Lock()
+				skip := failed && c.Bool("fail-fast")
+				failedMutex.Unlock()
+				if skip {
+					results[i] = execResult{name: p.Name, skipped: true}
+					continue
+				}
+
+				err := runner.run(p, stdin)
+				results[i] = execResult{name: p.Name, err: err}
+				if err != nil {
+					failedMutex.Lock()
+					failed = true
+					failedMutex.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range projects {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	succeeded, skipped := 0, 0
+	var failures []string
+	for _, result := range results {
+		switch {
+		case result.skipped:
+			skipped++
+		case result.err != nil:
+			failures = append(failures, fmt.Sprintf("%s (%s)", result.name, result.err.Error()))
+		default:
+			succeeded++
+		}
+	}
+
+	summary := fmt.Sprintf("%d succeeded, %d failed", succeeded, len(failures))
+	if skipped > 0 {
+		summary += fmt.Sprintf(", %d skipped", skipped)
+	}
+	log(c, "%s", summary)
+	if len(failures) > 0 {
+		log(c, "Failed: %s", strings.Join(failures, ", "))
+		return exitErrorWrapper("'%s' failed in %d projects", strings.Join(command, " "), len(failures))
+	}
+	return nil
+}
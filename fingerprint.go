@@ -0,0 +1,322 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/scan"
+	"github.com/Tebro/prj/vcs"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// markerFile holds the marker id of a project. It is kept inside the version
+// control metadata, so it moves with the checkout without showing up in the
+// working tree.
+const markerFile = "prj-id"
+
+func markerPath(dir string) string {
+	for _, meta := range []string{".jj", ".git", ".hg"} {
+		if isDir, _ := pathIsDir(filepath.Join(dir, meta)); isDir {
+			return filepath.Join(dir, meta, markerFile)
+		}
+	}
+	return ""
+}
+
+func readMarker(dir string) string {
+	path := markerPath(dir)
+	if path == "" {
+		return ""
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// ensureMarker returns the marker id of the project in dir, creating one if
+// the project has version control metadata but no id yet.
+func ensureMarker(dir string) string {
+	if id := readMarker(dir); id != "" {
+		return id
+	}
+	path := markerPath(dir)
+	if path == "" {
+		return ""
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	id := hex.EncodeToString(buf)
+	if err := ioutil.WriteFile(path, []byte(id+"\n"), 0644); err != nil {
+		return ""
+	}
+	return id
+}
+
+func takeFingerprint(dir string) (db.Fingerprint, error) {
+	var f db.Fingerprint
+	info, err := os.Stat(dir)
+	if err != nil {
+		return f, err
+	}
+	f.Device, f.Inode, _ = fileID(info)
+	if v, ok := vcs.Detect(dir); ok {
+		f.RootCommit, _ = v.RootCommit(dir)
+	}
+	f.MarkerID = ensureMarker(dir)
+	return f, nil
+}
+
+// recordFingerprint stores the current fingerprint of the project.
+func recordFingerprint(name string, dir string) {
+	if f, err := takeFingerprint(dir); err == nil {
+		db.SetProjectFingerprint(name, f)
+	}
+}
+
+// fingerprintProjects records fingerprints for the projects that don't have
+// one yet, which covers the projects a command registered as well as
+// registries from before fingerprints existed.
+func fingerprintProjects() {
+	for _, p := range db.GetProjects() {
+		if p.Fingerprint != nil {
+			continue
+		}
+		if isDir, _ := pathIsDir(p.Path); isDir {
+			recordFingerprint(p.Name, p.Path)
+		}
+	}
+}
+
+// registering wraps the action of a command that registers projects so they
+// are fingerprinted afterwards. Taking a fingerprint runs the VCS and writes
+// the marker, which other commands shouldn't do as a side effect.
+func registering(action func(*cli.Context) error) func(*cli.Context) error {
+	return func(c *cli.Context) error {
+		err := action(c)
+		fingerprintProjects()
+		return err
+	}
+}
+
+// Points for every part of a fingerprint that matches. A marker id is unique,
+// the inode is unique until the directory is copied or deleted and the root
+// commit is shared with other clones of the same repository.
+const (
+	markerPoints     = 4
+	inodePoints      = 2
+	rootCommitPoints = 1
+)
+
+type fingerprintIndex map[string][]string
+
+func (i fingerprintIndex) add(key string, name string) {
+	if key != "" {
+		i[key] = append(i[key], name)
+	}
+}
+
+// relinkMatch holds the best scoring directories found for a project.
+type relinkMatch struct {
+	dirs   []string
+	points int
+}
+
+// findRelinkCandidates searches the base dirs for unregistered directories
+// matching the fingerprints of projects. It returns the best scoring
+// directories for every project with at least one match.
+func findRelinkCandidates(c *cli.Context, projects []db.Project, depth int) map[string]relinkMatch {
+	markers, inodes, roots := fingerprintIndex{}, fingerprintIndex{}, fingerprintIndex{}
+	for _, p := range projects {
+		if p.Fingerprint == nil {
+			continue
+		}
+		markers.add(p.Fingerprint.MarkerID, p.Name)
+		if p.Fingerprint.Inode != 0 {
+			inodes.add(fmt.Sprintf("%d:%d", p.Fingerprint.Device, p.Fingerprint.Inode), p.Name)
+		}
+		roots.add(p.Fingerprint.RootCommit, p.Name)
+	}
+
+	registered := make(map[string]bool)
+	for _, p := range db.GetProjects() {
+		registered[absPath(p.Path)] = true
+	}
+
+	var mutex sync.Mutex
+	scores := make(map[string]map[string]int)
+	match := func(dir string) bool {
+		if registered[dir] {
+			return false
+		}
+		info, err := os.Stat(dir)
+		if err != nil {
+			return false
+		}
+
+		found := make(map[string]int)
+		for _, name := range markers[readMarker(dir)] {
+			found[name] += markerPoints
+		}
+		if device, inode, ok := fileID(info); ok {
+			for _, name := range inodes[fmt.Sprintf("%d:%d", device, inode)] {
+				found[name] += inodePoints
+			}
+		}
+		if v, ok := vcs.Detect(dir); ok && len(roots) > 0 {
+			if root, err := v.RootCommit(dir); err == nil {
+				for _, name := range roots[root] {
+					found[name] += rootCommitPoints
+				}
+			}
+		}
+		if len(found) == 0 {
+			return false
+		}
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		for name, points := range found {
+			if scores[name] == nil {
+				scores[name] = make(map[string]int)
+			}
+			scores[name][dir] = points
+		}
+		return true
+	}
+
+	opts := scan.Options{MaxDepth: depth, Ignore: scan.DefaultIgnore}
+	scan.Find(getBaseDirs(c), opts, match)
+
+	best := make(map[string]relinkMatch)
+	for name, dirs := range scores {
+		m := relinkMatch{}
+		for _, points := range dirs {
+			if points > m.points {
+				m.points = points
+			}
+		}
+		for dir, points := range dirs {
+			if points == m.points {
+				m.dirs = append(m.dirs, dir)
+			}
+		}
+		sort.Strings(m.dirs)
+		best[name] = m
+	}
+	return best
+}
+
+// relink searches for the directories of the given projects and updates the
+// entries that have exactly one best match, reporting them to out. Only the
+// marker id is unique: a directory matching on the inode could be a new one
+// that reused the inode of the deleted one and every clone shares the root
+// commit, so those matches are only taken when the user confirms them. It
+// returns the names of the projects that were relinked.
+func relink(c *cli.Context, out io.Writer, projects []db.Project, depth int) []string {
+	candidates := findRelinkCandidates(c, projects, depth)
+
+	var relinked []string
+	for _, p := range projects {
+		dirs := candidates[p.Name].dirs
+		points := candidates[p.Name].points
+		switch {
+		case p.Fingerprint == nil:
+			fmt.Fprintf(os.Stderr, "%s: no fingerprint recorded, use 'prj doctor --fix repoint' to search by name\n", p.Name)
+		case len(dirs) == 0:
+			fmt.Fprintf(os.Stderr, "%s: no matching directory found\n", p.Name)
+		case len(dirs) > 1:
+			fmt.Fprintf(os.Stderr, "%s: several matching directories found: %s\n", p.Name, strings.Join(dirs, ", "))
+		case points < markerPoints && !stdinIsTerminal():
+			fmt.Fprintf(os.Stderr, "%s: %s, run 'prj relink %s' in a terminal to confirm it\n", p.Name, weakMatch(points, dirs[0]), p.Name)
+		case points < markerPoints && !confirmTo(out, fmt.Sprintf("%s: %s, it may be a different directory. Relink?", p.Name, weakMatch(points, dirs[0]))):
+			fmt.Fprintf(os.Stderr, "%s: not relinked\n", p.Name)
+		default:
+			updateProjectPath(c, p.Name, dirs[0])
+			fmt.Fprintf(out, "Relinked %s to %s\n", p.Name, dirs[0])
+			relinked = append(relinked, p.Name)
+		}
+	}
+	return relinked
+}
+
+// weakMatch describes which parts of the fingerprint of dir add up to points,
+// for matches without the marker.
+func weakMatch(points int, dir string) string {
+	switch points {
+	case inodePoints:
+		return fmt.Sprintf("only the inode of %s matches", dir)
+	case rootCommitPoints:
+		return fmt.Sprintf("only the root commit of %s matches", dir)
+	}
+	return fmt.Sprintf("only the inode and root commit of %s match", dir)
+}
+
+func relinkProjects(c *cli.Context) error {
+	var projects []db.Project
+	if c.NArg() > 0 {
+		for _, name := range c.Args() {
+			p, err := db.GetProject(name)
+			if err != nil {
+				return exitErrorWrapper("could not relink '%s': %s", name, err.Error())
+			}
+			projects = append(projects, p)
+		}
+	} else {
+		for _, p := range db.GetProjects() {
+			if exists, _ := pathExists(p.Path); !exists {
+				projects = append(projects, p)
+			}
+		}
+	}
+	if len(projects) == 0 {
+		log(c, "No missing projects")
+		return nil
+	}
+
+	relinked := relink(c, c.App.Writer, projects, c.Int("depth"))
+	if len(relinked) < len(projects) {
+		db.PrepareForShutdown()
+		return exitErrorWrapper("relinked %d of %d projects", len(relinked), len(projects))
+	}
+	return nil
+}
+
+// gotoSearchDepth limits the search for a missing project started from goto.
+const gotoSearchDepth = 4
+
+// offerRelink asks whether to search for a project whose directory is
+// missing. Questions go to stderr because stdout of goto is eval'ed.
+func offerRelink(c *cli.Context, name string, path string) (string, bool) {
+	fmt.Fprintf(os.Stderr, "The directory of %s (%s) does not exist.\n", name, path)
+	if !stdinIsTerminal() {
+		fmt.Fprintf(os.Stderr, "Run 'prj relink %s' to search for it.\n", name)
+		return "", false
+	}
+
+	fmt.Fprintf(os.Stderr, "Search the base dirs for it? [y/N] ")
+	answer := ""
+	fmt.Fscanln(os.Stdin, &answer)
+	if answer = strings.ToLower(answer); answer != "y" && answer != "yes" {
+		return "", false
+	}
+
+	p, err := db.GetProject(name)
+	if err != nil || len(relink(c, os.Stderr, []db.Project{p}, gotoSearchDepth)) == 0 {
+		return "", false
+	}
+	db.PrepareForShutdown()
+	path, err = db.GetProjectDir(name)
+	return path, err == nil
+}
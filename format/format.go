@@ -0,0 +1,198 @@
+// Package format renders records, slices of structs, as JSON, YAML, CSV, TSV,
+// aligned tables or Go templates. The field names in every format are the
+// names of the struct fields, so they stay stable for scripts.
+package format
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Names lists the built in formats, anything else is treated as a template.
+var Names = []string{"json", "yaml", "csv", "tsv", "table"}
+
+// Options control how records are written.
+type Options struct {
+	// Format is one of Names or a Go template executed for every record,
+	// e.g. '{{.Name}}\t{{.Path}}'. The escapes \t and \n are understood.
+	Format string
+	// NoHeader leaves out the header line of table, csv and tsv output.
+	NoHeader bool
+}
+
+// field is an exported struct field.
+type field struct {
+	name  string
+	index int
+}
+
+func fields(t reflect.Type) ([]field, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't format %s, expected a struct", t)
+	}
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" {
+			out = append(out, field{name: f.Name, index: i})
+		}
+	}
+	return out, nil
+}
+
+// text formats a field value for the text based formats. Structs and maps
+// that don't format themselves are written as compact JSON.
+func text(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		var items []string
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, text(v.Index(i)))
+		}
+		return strings.Join(items, ",")
+	}
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		return text(v.Elem())
+	}
+	if _, ok := v.Interface().(fmt.Stringer); !ok && (v.Kind() == reflect.Struct || v.Kind() == reflect.Map) {
+		data, err := json.Marshal(v.Interface())
+		if err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Write renders records, which must be a slice of structs, to w.
+func Write(w io.Writer, records interface{}, opts Options) error {
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("can't format %s, expected a slice", v.Type())
+	}
+	fs, err := fields(v.Type().Elem())
+	if err != nil {
+		return err
+	}
+
+	switch opts.Format {
+	case "json":
+		if v.Len() == 0 {
+			_, err := fmt.Fprintln(w, "[]")
+			return err
+		}
+		return writeJSON(w, records)
+	case "yaml":
+		return writeYAML(w, records)
+	case "csv", "tsv", "table":
+		var rows [][]string
+		if !opts.NoHeader {
+			var header []string
+			for _, f := range fs {
+				if opts.Format == "table" {
+					header = append(header, strings.ToUpper(f.name))
+				} else {
+					header = append(header, f.name)
+				}
+			}
+			rows = append(rows, header)
+		}
+		for i := 0; i < v.Len(); i++ {
+			var row []string
+			for _, f := range fs {
+				row = append(row, text(v.Index(i).Field(f.index)))
+			}
+			rows = append(rows, row)
+		}
+		return writeRows(w, opts.Format, rows)
+	default:
+		return writeTemplate(w, v, opts.Format)
+	}
+}
+
+// WriteRecord renders a single struct. JSON and YAML output is an object
+// instead of a list with one element, the other formats are the same as for
+// Write.
+func WriteRecord(w io.Writer, record interface{}, opts Options) error {
+	switch opts.Format {
+	case "json":
+		return writeJSON(w, record)
+	case "yaml":
+		if _, err := fields(reflect.TypeOf(record)); err != nil {
+			return err
+		}
+		return writeYAML(w, record)
+	}
+
+	list := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(record)), 1, 1)
+	list.Index(0).Set(reflect.ValueOf(record))
+	return Write(w, list.Interface(), opts)
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	// Queries and templates contain < and >, which should stay readable.
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeRows(w io.Writer, format string, rows [][]string) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.WriteAll(rows)
+		return cw.Error()
+	case "tsv":
+		// Tabs and newlines inside values would break the columns.
+		clean := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+		for _, row := range rows {
+			for i := range row {
+				row[i] = clean.Replace(row[i])
+			}
+			if _, err := fmt.Fprintln(w, strings.Join(row, "\t")); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		for _, row := range rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	}
+}
+
+func writeTemplate(w io.Writer, v reflect.Value, format string) error {
+	if !strings.Contains(format, "{{") {
+		return fmt.Errorf("unknown format '%s', expected one of %s or a Go template", format, strings.Join(Names, ", "))
+	}
+	format = strings.NewReplacer(`\t`, "\t", `\n`, "\n").Replace(format)
+	funcs := template.FuncMap{
+		"join": strings.Join,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+	t, err := template.New("format").Funcs(funcs).Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid format, expected one of %s or a Go template: %s", strings.Join(Names, ", "), err.Error())
+	}
+	for i := 0; i < v.Len(); i++ {
+		if err := t.Execute(w, v.Index(i).Interface()); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
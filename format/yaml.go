@@ -0,0 +1,77 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"gopkg.in/yaml.v2"
+	"io"
+	"strconv"
+)
+
+// writeYAML writes v as YAML with the same keys, in the same order, as the
+// JSON output.
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	doc, err := ordered(decoder)
+	if err != nil {
+		return err
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// ordered reads the next JSON value from decoder. Objects become
+// yaml.MapSlice values so their keys keep the order of the struct fields.
+func ordered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := token.(type) {
+	case json.Delim:
+		if t == '{' {
+			doc := yaml.MapSlice{}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := ordered(decoder)
+				if err != nil {
+					return nil, err
+				}
+				doc = append(doc, yaml.MapItem{Key: key, Value: value})
+			}
+			_, err := decoder.Token()
+			return doc, err
+		}
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := ordered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := decoder.Token()
+		return list, err
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(t.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		return t.Float64()
+	}
+	return token, nil
+}
@@ -0,0 +1,76 @@
+package format
+
+import (
+	"bytes"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type yamlNested struct {
+	Hash string `yaml:"Hash"`
+	Size int64  `yaml:"Size"`
+}
+
+type yamlRecord struct {
+	Name    string      `yaml:"Name"`
+	Tags    []string    `yaml:"Tags"`
+	Count   int         `yaml:"Count"`
+	Enabled bool        `yaml:"Enabled"`
+	Nested  *yamlNested `yaml:"Nested"`
+}
+
+// Strings YAML would read as something else if they were written plainly.
+var ambiguous = []string{
+	"2026-10-18", "2026-10-18T10:00:00Z", "1_000", "012", "0x1F", "1e3", ".inf", "+1",
+	"yes", "No", "on", "OFF", "y", "n", "true", "null", "~", "",
+	" leading", "trailing ", "a: b", "#comment", "- item", "multi\nline", `quote"s`, "[x]", "{x}", "&anchor", "*alias", "!tag", "%d", "@at",
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	var records []yamlRecord
+	for i, s := range ambiguous {
+		records = append(records, yamlRecord{Name: s, Tags: []string{s, "plain"}, Count: i, Enabled: i%2 == 0, Nested: &yamlNested{Hash: s, Size: 1 << 40}})
+	}
+	records = append(records, yamlRecord{Name: "empty", Tags: []string{}})
+
+	var buf bytes.Buffer
+	if err := Write(&buf, records, Options{Format: "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	var back []yamlRecord
+	if err := yaml.UnmarshalStrict(buf.Bytes(), &back); err != nil {
+		t.Fatalf("%s\n%s", err, buf.String())
+	}
+	if !reflect.DeepEqual(back, records) {
+		t.Errorf("read back %+v, want %+v\n%s", back, records, buf.String())
+	}
+}
+
+func TestYAMLRecord(t *testing.T) {
+	var buf bytes.Buffer
+	record := yamlRecord{Name: "null", Tags: []string{"1_000"}, Count: 3}
+	if err := WriteRecord(&buf, record, Options{Format: "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "Name: \"null\"\nTags:\n- \"1_000\"\nCount: 3\nEnabled: false\nNested: null\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+
+	var back yamlRecord
+	if err := yaml.UnmarshalStrict(buf.Bytes(), &back); err != nil || !reflect.DeepEqual(back, record) {
+		t.Errorf("read back %+v, %v, want %+v", back, err, record)
+	}
+}
+
+func TestYAMLEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, []yamlRecord{}, Options{Format: "yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("got %q, want []", buf.String())
+	}
+}
@@ -0,0 +1,217 @@
+package fsutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IsArchive reports whether filename has the extension of a supported
+// archive: .tar, .tar.gz, .tgz or .zip.
+func IsArchive(filename string) bool {
+	lower := strings.ToLower(filename)
+	for _, ext := range []string{".tar", ".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract unpacks the archive into the existing directory dst. Entries that
+// would end up outside of dst, directly or through a symlink, are refused.
+func Extract(archive string, dst string) error {
+	dst = filepath.Clean(dst)
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		return extractZip(archive, dst)
+	}
+	return extractTar(archive, dst)
+}
+
+// ContentRoot returns the single top level directory in dir if there is
+// nothing else in dir, which is how most archives are laid out. Otherwise dir
+// itself is returned.
+func ContentRoot(dir string) string {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
+}
+
+// within reports whether path is dir or below it. Both must be clean.
+func within(dir string, path string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(os.PathSeparator))
+}
+
+// safeJoin joins name to dir, refusing names that would escape dir. That
+// includes writing through a symlink created by an earlier entry, so neither
+// the path itself nor any of its parents below dir may be a symlink.
+func safeJoin(dir string, name string) (string, error) {
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if !within(dir, target) {
+		return "", fmt.Errorf("archive entry '%s' points outside of the target directory", name)
+	}
+
+	rel, err := filepath.Rel(dir, target)
+	if err != nil || rel == "." {
+		return target, err
+	}
+	current := dir
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("archive entry '%s' would be written through a symlink", name)
+		}
+	}
+	return target, nil
+}
+
+// checkLink refuses a symlink entry name that points to an absolute path or
+// outside of dir.
+func checkLink(dir string, name string, linkname string) error {
+	link := filepath.FromSlash(linkname)
+	if filepath.IsAbs(link) || strings.HasPrefix(linkname, "/") || filepath.VolumeName(link) != "" {
+		return fmt.Errorf("archive entry '%s' is a symlink to the absolute path '%s'", name, linkname)
+	}
+	target := filepath.Join(dir, filepath.FromSlash(name))
+	if !within(dir, filepath.Join(filepath.Dir(target), link)) {
+		return fmt.Errorf("archive entry '%s' is a symlink pointing outside of the target directory", name)
+	}
+	return nil
+}
+
+func createLink(dir string, name string, target string, linkname string) error {
+	if err := checkLink(dir, name, linkname); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(linkname, target)
+}
+
+func extractTar(archive string, dst string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	lower := strings.ToLower(archive)
+	if strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(dst, header.Name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, mode|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := writeEntry(target, tr, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := createLink(dst, header.Name, target, header.Linkname); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func extractZip(archive string, dst string) error {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		target, err := safeJoin(dst, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, f.Mode().Perm()|0700); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			// Symlinks are stored with their target as the content.
+			var linkname []byte
+			linkname, err = ioutil.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+			if err := createLink(dst, f.Name, target, string(linkname)); err != nil {
+				return err
+			}
+			continue
+		}
+		err = writeEntry(target, rc, f.Mode().Perm())
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeEntry(target string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,135 @@
+package fsutil
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	linkname string
+	body     string
+}
+
+func writeTar(t *testing.T, path string, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tw := tar.NewWriter(f)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.body))}
+		if e.linkname != "" {
+			header = &tar.Header{Name: e.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: e.linkname}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, path string, entries []entry) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name}
+		header.SetMode(0644)
+		body := e.body
+		if e.linkname != "" {
+			header.SetMode(os.ModeSymlink | 0777)
+			body = e.linkname
+		}
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractRefusesEscapes(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+	}{
+		{"parent directory", []entry{{name: "../pwn", body: "x"}}},
+		{"absolute symlink", []entry{{name: "l", linkname: "/tmp"}}},
+		{"relative symlink outside", []entry{{name: "a/l", linkname: "../../outside"}}},
+		{"write through symlink", []entry{{name: "l", linkname: "a"}, {name: "l/pwn", body: "x"}}},
+		{"overwrite symlink", []entry{{name: "l", linkname: "file"}, {name: "l", body: "x"}}},
+	}
+
+	for _, test := range tests {
+		for _, format := range []string{"tar", "zip"} {
+			t.Run(test.name+" "+format, func(t *testing.T) {
+				dir := t.TempDir()
+				archive := filepath.Join(dir, "a."+format)
+				dst := filepath.Join(dir, "dst")
+				if err := os.Mkdir(dst, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if format == "tar" {
+					writeTar(t, archive, test.entries)
+				} else {
+					writeZip(t, archive, test.entries)
+				}
+
+				if err := Extract(archive, dst); err == nil {
+					t.Errorf("expected an error")
+				}
+				if _, err := os.Lstat(filepath.Join(dir, "pwn")); err == nil {
+					t.Errorf("an entry was written outside of the target directory")
+				}
+			})
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	for _, format := range []string{"tar", "zip"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "a."+format)
+			entries := []entry{
+				{name: "p/src/main.go", body: "package main"},
+				{name: "p/link", linkname: "src/main.go"},
+				{name: "p/src/up", linkname: "../link"},
+			}
+			if format == "tar" {
+				writeTar(t, archive, entries)
+			} else {
+				writeZip(t, archive, entries)
+			}
+
+			if err := Extract(archive, dir+string(os.PathSeparator)); err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadFile(filepath.Join(dir, "p", "src", "up"))
+			if err != nil || string(data) != "package main" {
+				t.Errorf("got %q, %v reading through the links", data, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+// Package fsutil contains file system helpers shared by the prj commands.
+package fsutil
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/gitignore"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// VCSDirs are the metadata directories and files of the supported version
+// control systems.
+var VCSDirs = []string{".git", ".hg", ".jj", ".fslckout", "_FOSSIL_", ".svn", ".bzr"}
+
+// IsVCSMetadata reports whether name is a version control metadata entry.
+func IsVCSMetadata(name string) bool {
+	for _, d := range VCSDirs {
+		if name == d {
+			return true
+		}
+	}
+	return false
+}
+
+// CopyOptions controls which files CopyTree copies and how.
+type CopyOptions struct {
+	// SkipVCS leaves out version control metadata such as .git.
+	SkipVCS bool
+	// Gitignore leaves out files ignored by .gitignore files in the tree.
+	Gitignore bool
+	// Rename, if set, maps the path of every entry (relative to src) to its
+	// path relative to dst.
+	Rename func(rel string) string
+	// Rewrite, if set, is called for every regular file with its renamed path
+	// and may change its final path and contents.
+	Rewrite func(rel string, data []byte) (string, []byte, error)
+}
+
+// CopyTree copies the directory src to dst, creating dst if needed.
+// Permissions and symlinks are preserved.
+func CopyTree(src string, dst string, opts CopyOptions) error {
+	stat, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !stat.IsDir() {
+		return fmt.Errorf("'%s' is not a directory", src)
+	}
+
+	ignore := gitignore.New()
+	if opts.Gitignore {
+		if err := ignore.AddFile("", filepath.Join(src, ".git", "info", "exclude")); err != nil {
+			return err
+		}
+	}
+
+	return filepath.Walk(src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			if opts.Gitignore {
+				if err := ignore.AddDir("", file); err != nil {
+					return err
+				}
+			}
+			return os.MkdirAll(dst, info.Mode().Perm())
+		}
+		slashed := filepath.ToSlash(rel)
+
+		if opts.SkipVCS && IsVCSMetadata(info.Name()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if opts.Gitignore && ignore.Match(slashed, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if opts.Rename != nil {
+			rel = opts.Rename(rel)
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			if opts.Gitignore {
+				if err := ignore.AddDir(slashed, file); err != nil {
+					return err
+				}
+			}
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(file)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case info.Mode().IsRegular():
+			return copyFile(file, target, rel, info.Mode().Perm(), dst, opts)
+		}
+		return nil
+	})
+}
+
+func copyFile(src string, target string, rel string, perm os.FileMode, dst string, opts CopyOptions) error {
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	if opts.Rewrite != nil {
+		newRel, newData, err := opts.Rewrite(rel, data)
+		if err != nil {
+			return err
+		}
+		target = filepath.Join(dst, newRel)
+		data = newData
+	}
+
+	return ioutil.WriteFile(target, data, perm)
+}
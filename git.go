@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/gitignore"
+	"github.com/Tebro/prj/vcs"
+	"gopkg.in/urfave/cli.v1"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type gitOptions struct {
+	Branch        string
+	InitialCommit bool
+	Gitignore     []string
+	Remote        string
+	Identity      db.GitIdentity
+	HasIdentity   bool
+}
+
+func usesGitOptions(c *cli.Context) bool {
+	return c.Bool("initial-commit") || len(c.StringSlice("gitignore")) > 0 || len(c.String("remote")) > 0
+}
+
+func shouldCreateGit(c *cli.Context) bool {
+	return c.Bool("git") || db.GetConfigAlwaysGit() || len(c.String("branch")) > 0 || usesGitOptions(c)
+}
+
+// getNewVCS returns the version control system to create for a new project,
+// or nil if none should be created.
+func getNewVCS(c *cli.Context) (vcs.VCS, error) {
+	var v vcs.VCS
+	switch {
+	case len(c.String("vcs")) > 0:
+		selected, err := vcs.Get(c.String("vcs"))
+		if err != nil {
+			return nil, err
+		}
+		if selected.Name() != "git" && usesGitOptions(c) {
+			return nil, fmt.Errorf("--initial-commit, --gitignore and --remote are only supported with git")
+		}
+		v = selected
+	case shouldCreateGit(c):
+		v = vcs.Git{}
+	default:
+		return nil, nil
+	}
+
+	return v, vcs.Available(v)
+}
+
+func getGitOptions(c *cli.Context, categories []string) (gitOptions, error) {
+	opts := gitOptions{
+		Branch:        db.GetConfigDefaultBranch(),
+		InitialCommit: db.GetConfigInitialCommit() || c.Bool("initial-commit"),
+		Remote:        c.String("remote"),
+	}
+	if len(c.String("branch")) > 0 {
+		opts.Branch = c.String("branch")
+	}
+
+	for _, value := range c.StringSlice("gitignore") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				opts.Gitignore = append(opts.Gitignore, name)
+			}
+		}
+	}
+	// Validate the presets before anything is created
+	if _, err := gitignore.Generate(opts.Gitignore); err != nil {
+		return opts, err
+	}
+
+	opts.Identity, opts.HasIdentity = db.GetGitIdentity(categories)
+
+	return opts, nil
+}
+
+func runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// initGitRepository creates a git repository in dir according to opts and
+// returns a summary of what was done.
+func initGitRepository(dir string, opts gitOptions) ([]string, error) {
+	var summary []string
+
+	if err := (vcs.Git{}).Init(dir, opts.Branch); err != nil {
+		return summary, err
+	}
+	summary = append(summary, "Created Git repository")
+	if opts.Branch != "" {
+		summary = append(summary, fmt.Sprintf("  branch: %s", opts.Branch))
+	}
+
+	if opts.HasIdentity {
+		if _, err := runGit(dir, "config", "user.name", opts.Identity.Name); err != nil {
+			return summary, err
+		}
+		if _, err := runGit(dir, "config", "user.email", opts.Identity.Email); err != nil {
+			return summary, err
+		}
+		summary = append(summary, fmt.Sprintf("  identity: %s", opts.Identity))
+	}
+
+	if len(opts.Gitignore) > 0 {
+		if err := appendGitignore(dir, opts.Gitignore); err != nil {
+			return summary, err
+		}
+		summary = append(summary, fmt.Sprintf("  .gitignore: %s", strings.Join(opts.Gitignore, ", ")))
+	}
+
+	if opts.Remote != "" {
+		if _, err := runGit(dir, "remote", "add", "origin", opts.Remote); err != nil {
+			return summary, err
+		}
+		summary = append(summary, fmt.Sprintf("  remote origin: %s", opts.Remote))
+	}
+
+	if opts.InitialCommit {
+		if _, err := runGit(dir, "add", "-A"); err != nil {
+			return summary, err
+		}
+		if _, err := runGit(dir, "commit", "--allow-empty", "-m", "Initial commit"); err != nil {
+			return summary, err
+		}
+		hash, err := runGit(dir, "rev-parse", "--short", "HEAD")
+		if err != nil {
+			return summary, err
+		}
+		summary = append(summary, fmt.Sprintf("  initial commit: %s", hash))
+	}
+
+	return summary, nil
+}
+
+// initRepository creates a repository of any version control system in dir.
+func initRepository(v vcs.VCS, dir string, opts gitOptions) ([]string, error) {
+	if v.Name() == "git" {
+		return initGitRepository(dir, opts)
+	}
+
+	if err := v.Init(dir, opts.Branch); err != nil {
+		return nil, err
+	}
+	return []string{fmt.Sprintf("Created %s repository", v.Name())}, nil
+}
+
+// appendGitignore adds the named presets to the .gitignore in dir, keeping
+// any existing content, e.g. from a template.
+func appendGitignore(dir string, presets []string) error {
+	content, err := gitignore.Generate(presets)
+	if err != nil {
+		return err
+	}
+
+	filename := filepath.Join(dir, ".gitignore")
+	existing, err := ioutil.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(existing) > 0 {
+		content = strings.TrimRight(string(existing), "\n") + "\n\n" + content
+	}
+
+	return ioutil.WriteFile(filename, []byte(content), 0644)
+}
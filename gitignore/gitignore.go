@@ -0,0 +1,147 @@
+// Package gitignore matches paths against the patterns of .gitignore files.
+package gitignore
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type pattern struct {
+	base     string
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Matcher holds the patterns of any number of .gitignore files. Patterns
+// added later take precedence, as in git.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New returns an empty Matcher.
+func New() *Matcher {
+	return &Matcher{}
+}
+
+// AddPatterns adds gitignore patterns that apply to base and everything
+// below it. base is a slash separated path relative to the root of the tree,
+// or the empty string for the root itself.
+func (m *Matcher) AddPatterns(base string, lines []string) {
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p := pattern{base: base}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimLeft(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		p.segments = strings.Split(line, "/")
+		m.patterns = append(m.patterns, p)
+	}
+}
+
+// AddFile reads the patterns of the gitignore style file at filename. A
+// missing file is not an error.
+func (m *Matcher) AddFile(base string, filename string) error {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	m.AddPatterns(base, lines)
+	return nil
+}
+
+// AddDir adds the .gitignore file of dir, which is the directory base relative
+// to the root of the tree.
+func (m *Matcher) AddDir(base string, dir string) error {
+	return m.AddFile(base, filepath.Join(dir, ".gitignore"))
+}
+
+// Match reports whether the slash separated path rel, relative to the root of
+// the tree, is ignored.
+func (m *Matcher) Match(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		if p.matches(rel, isDir) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func (p pattern) matches(rel string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+
+	if p.base != "" {
+		if !strings.HasPrefix(rel, p.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, p.base+"/")
+	}
+	name := strings.Split(rel, "/")
+
+	if !p.anchored {
+		ok, err := path.Match(p.segments[0], name[len(name)-1])
+		return err == nil && ok
+	}
+	return matchSegments(p.segments, name)
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
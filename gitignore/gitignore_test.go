@@ -0,0 +1,146 @@
+package gitignore
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+type matchTest struct {
+	rel   string
+	isDir bool
+	want  bool
+}
+
+func checkMatches(t *testing.T, m *Matcher, tests []matchTest) {
+	t.Helper()
+	for _, test := range tests {
+		if got := m.Match(test.rel, test.isDir); got != test.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", test.rel, test.isDir, got, test.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		matches  []matchTest
+	}{
+		{
+			name:     "unanchored patterns match at any depth",
+			patterns: []string{"*.o", "# comment", "", "tmp"},
+			matches: []matchTest{
+				{"main.o", false, true},
+				{"src/lib/x.o", false, true},
+				{"main.c", false, false},
+				{"tmp", true, true},
+				{"a/tmp", false, true},
+				{"# comment", false, false},
+			},
+		},
+		{
+			name:     "anchored patterns match relative to the root",
+			patterns: []string{"/build", "doc/*.txt"},
+			matches: []matchTest{
+				{"build", true, true},
+				{"src/build", true, false},
+				{"doc/a.txt", false, true},
+				{"doc/sub/a.txt", false, false},
+				{"x/doc/a.txt", false, false},
+			},
+		},
+		{
+			name:     "directory only patterns",
+			patterns: []string{"out/", "/dist/"},
+			matches: []matchTest{
+				{"out", true, true},
+				{"out", false, false},
+				{"a/out", true, true},
+				{"dist", true, true},
+				{"dist", false, false},
+				{"a/dist", true, false},
+			},
+		},
+		{
+			name:     "double star",
+			patterns: []string{"**/node_modules/", "a/**/b", "logs/**/*.log"},
+			matches: []matchTest{
+				{"node_modules", true, true},
+				{"web/app/node_modules", true, true},
+				{"a/b", false, true},
+				{"a/x/y/b", false, true},
+				{"c/a/b", false, false},
+				{"logs/today.log", false, true},
+				{"logs/2020/01/today.log", false, true},
+				{"logs/today.txt", false, false},
+			},
+		},
+		{
+			name:     "negation re-includes, later patterns win",
+			patterns: []string{"*.log", "!keep.log", "debug/keep.log"},
+			matches: []matchTest{
+				{"a.log", false, true},
+				{"keep.log", false, false},
+				{"src/keep.log", false, false},
+				{"debug/keep.log", false, true},
+			},
+		},
+		{
+			name:     "escaped and trailing whitespace",
+			patterns: []string{`\!important`, `\#file`, "spaced   "},
+			matches: []matchTest{
+				{"!important", false, true},
+				{"#file", false, true},
+				{"spaced", false, true},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := New()
+			m.AddPatterns("", test.patterns)
+			checkMatches(t, m, test.matches)
+		})
+	}
+}
+
+func TestMatchNested(t *testing.T) {
+	m := New()
+	m.AddPatterns("", []string{"*.tmp", "/bin"})
+	m.AddPatterns("sub", []string{"!keep.tmp", "/gen", "cache/"})
+
+	checkMatches(t, m, []matchTest{
+		{"x.tmp", false, true},
+		{"keep.tmp", false, true},
+		{"sub/keep.tmp", false, false},
+		{"sub/deep/keep.tmp", false, false},
+		{"bin", true, true},
+		{"sub/bin", true, false},
+		{"sub/gen", true, true},
+		{"sub/x/gen", true, false},
+		{"gen", true, false},
+		{"sub/a/cache", true, true},
+		{"cache", true, false},
+	})
+}
+
+func TestAddDir(t *testing.T) {
+	dir := t.TempDir()
+	m := New()
+	if err := m.AddDir("", dir); err != nil {
+		t.Fatalf("missing .gitignore: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".gitignore"), []byte("vendor/\r\n*.swp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddDir("", dir); err != nil {
+		t.Fatal(err)
+	}
+	checkMatches(t, m, []matchTest{
+		{"vendor", true, true},
+		{"a.swp", false, true},
+		{"a.go", false, false},
+	})
+}
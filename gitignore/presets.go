@@ -0,0 +1,134 @@
+package gitignore
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var presets = map[string]string{
+	"go": `# Binaries
+*.exe
+*.exe~
+*.dll
+*.so
+*.dylib
+/bin/
+
+# Test binaries and coverage
+*.test
+*.out
+coverage.*
+
+# Workspace file
+go.work
+go.work.sum
+`,
+	"node": `node_modules/
+npm-debug.log*
+yarn-debug.log*
+yarn-error.log*
+pnpm-debug.log*
+.npm
+.yarn/cache
+.pnp.*
+dist/
+build/
+coverage/
+.env
+.env.*.local
+`,
+	"python": `__pycache__/
+*.py[cod]
+*.egg-info/
+.eggs/
+build/
+dist/
+.venv/
+venv/
+.pytest_cache/
+.mypy_cache/
+.ruff_cache/
+.tox/
+.coverage
+htmlcov/
+`,
+	"rust": `/target/
+**/*.rs.bk
+`,
+	"java": `*.class
+*.jar
+*.war
+*.ear
+hs_err_pid*
+target/
+build/
+.gradle/
+out/
+`,
+	"c": `*.o
+*.obj
+*.a
+*.lib
+*.so
+*.so.*
+*.dylib
+*.dll
+*.exe
+*.out
+*.d
+build/
+`,
+	"macos": `.DS_Store
+.AppleDouble
+.LSOverride
+._*
+`,
+	"windows": `Thumbs.db
+ehthumbs.db
+Desktop.ini
+$RECYCLE.BIN/
+`,
+	"linux": `*~
+.directory
+.Trash-*
+`,
+	"vim": `[._]*.s[a-w][a-z]
+[._]s[a-w][a-z]
+Session.vim
+tags
+`,
+	"vscode": `.vscode/*
+!.vscode/settings.json
+!.vscode/tasks.json
+!.vscode/launch.json
+!.vscode/extensions.json
+`,
+	"jetbrains": `.idea/
+*.iml
+`,
+}
+
+// Presets returns the names of the bundled .gitignore presets.
+func Presets() []string {
+	var names []string
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Generate concatenates the named presets into the contents of a .gitignore
+// file.
+func Generate(names []string) (string, error) {
+	var sections []string
+	for _, name := range names {
+		preset, ok := presets[strings.ToLower(name)]
+		if !ok {
+			return "", fmt.Errorf("unknown gitignore preset '%s', available: %s", name, strings.Join(Presets(), ", "))
+		}
+		sections = append(sections, fmt.Sprintf("# %s\n%s", name, preset))
+	}
+	return strings.Join(sections, "\n"), nil
+}
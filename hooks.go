@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/hooks"
+	"github.com/Tebro/prj/manifest"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// projectCategories derives the categories of a project from the directories
+// between the base dir and the project directory.
+func projectCategories(c *cli.Context, path string) []string {
+	rel, err := filepath.Rel(getBaseDir(c), path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+
+	dir := filepath.Dir(rel)
+	if dir == "." {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(dir), "/")
+}
+
+// splitCategories splits categories given as a/b as well as -c a -c b into
+// their path segments.
+func splitCategories(values []string) []string {
+	var categories []string
+	for _, value := range values {
+		for _, category := range strings.Split(filepath.ToSlash(value), "/") {
+			if category != "" {
+				categories = append(categories, category)
+			}
+		}
+	}
+	return categories
+}
+
+// getProjectCategories returns the categories stored for a project, or
+// derives them from its path for projects registered before categories were
+// stored.
+func getProjectCategories(c *cli.Context, p db.Project) []string {
+	if len(p.Categories) > 0 {
+		return p.Categories
+	}
+	return projectCategories(c, p.Path)
+}
+
+// hookProject describes a project for hooks. Its project hooks only run if it
+// is registered at path and the user allowed its manifest.
+func hookProject(c *cli.Context, name string, path string, categories []string) hooks.Project {
+	hp := hooks.Project{
+		Name:       name,
+		Path:       path,
+		Categories: categories,
+		BaseDir:    getBaseDir(c),
+		Command:    c.Command.Name,
+	}
+	if p, err := db.GetProject(name); err == nil && p.Path == path {
+		hp.AllowedManifest = p.HooksAllowed
+	}
+	return hp
+}
+
+func runHook(c *cli.Context, stdout io.Writer, event string, p hooks.Project) error {
+	runner := hooks.Runner{
+		Dir:    db.GetHooksDir(),
+		Stdout: stdout,
+		Stderr: os.Stderr,
+	}
+	return runner.Run(event, p)
+}
+
+// runPreHook fires the pre event of operation. The returned error is meant to
+// abort the operation.
+func runPreHook(c *cli.Context, operation string, p hooks.Project) error {
+	err := runHook(c, c.App.Writer, hooks.Pre(operation), p)
+	if err != nil {
+		return exitErrorWrapper("aborted by %s hook: %s", hooks.Pre(operation), err.Error())
+	}
+	return nil
+}
+
+// runPostHook fires the post event of operation. The operation has already
+// happened, so failures are only reported.
+func runPostHook(c *cli.Context, operation string, p hooks.Project) {
+	err := runHook(c, c.App.Writer, hooks.Post(operation), p)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s hook failed: %s\n", hooks.Post(operation), err.Error())
+	}
+}
+
+// hooksTarget returns the project named by the only argument, or the project
+// containing the working directory.
+func hooksTarget(c *cli.Context) (db.Project, error) {
+	switch c.NArg() {
+	case 0:
+		p, _, err := currentProject("")
+		return p, err
+	case 1:
+		p, err := db.GetProject(c.Args()[0])
+		if err != nil {
+			return p, exitErrorWrapper("%s", err.Error())
+		}
+		return p, nil
+	}
+	return db.Project{}, exitErrorWrapper("invalid number of arguments, expected 0 or 1")
+}
+
+// allowHooks lets the hooks in the current manifest of a project run. They
+// have to be allowed again whenever the manifest changes.
+func allowHooks(c *cli.Context) error {
+	p, err := hooksTarget(c)
+	if err != nil {
+		return err
+	}
+	m, err := manifest.Load(p.Path)
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+	if len(m.Hooks) == 0 {
+		return exitErrorWrapper("project '%s' has no hooks in %s", p.Name, manifest.FileName)
+	}
+
+	var events []string
+	for event := range m.Hooks {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	log(c, "Allowing the hooks of '%s':", p.Name)
+	for _, event := range events {
+		for _, command := range m.Hooks[event] {
+			log(c, "  %s: %s", event, command)
+		}
+	}
+	return db.SetProjectHooksAllowed(p.Name, m.Hash)
+}
+
+func denyHooks(c *cli.Context) error {
+	p, err := hooksTarget(c)
+	if err != nil {
+		return err
+	}
+	if err := db.SetProjectHooksAllowed(p.Name, ""); err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+	log(c, "The hooks of '%s' no longer run", p.Name)
+	return nil
+}
+
+// archiveOne archives a single project, running the archive hooks.
+func archiveOne(c *cli.Context, project db.Project) error {
+	if project.Archived {
+		return exitErrorWrapper("project '%s' is already archived", project.Name)
+	}
+
+	hp := hookProject(c, project.Name, project.Path, getProjectCategories(c, project))
+	if err := runPreHook(c, hooks.Archive, hp); err != nil {
+		return err
+	}
+
+	db.SetProjectArchived(project.Name, true)
+	log(c, "Project: '%s' archived", project.Name)
+
+	runPostHook(c, hooks.Archive, hp)
+	return nil
+}
+
+func archiveProject(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return exitErrorWrapper("invalid number of arguments, expected a project name or a query")
+	}
+
+	projects, err := selectTargets(c, db.GetProjectList(false))
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return exitErrorWrapper("no projects selected")
+	}
+	if len(projects) == 1 {
+		return archiveOne(c, projects[0])
+	}
+
+	failed := 0
+	for _, project := range projects {
+		if err := archiveOne(c, project); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", project.Name, err.Error())
+			failed++
+		}
+	}
+	if failed > 0 {
+		db.PrepareForShutdown()
+		return exitErrorWrapper("%d of %d projects not archived", failed, len(projects))
+	}
+	return nil
+}
+
+func unarchiveProject(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return exitErrorWrapper("invalid number of arguments, expected a project name or a query")
+	}
+
+	var archived []db.Project
+	for _, p := range db.GetProjectList(true) {
+		if p.Archived {
+			archived = append(archived, p)
+		}
+	}
+	projects, err := selectTargets(c, archived)
+	if err != nil {
+		return err
+	}
+	if len(projects) == 0 {
+		return exitErrorWrapper("no projects selected")
+	}
+
+	for _, project := range projects {
+		if err := db.SetProjectArchived(project.Name, false); err != nil {
+			return exitErrorWrapper("could not unarchive project: %s", err.Error())
+		}
+		log(c, "Project: '%s' unarchived", project.Name)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package hooks runs user defined scripts before and after prj operations.
+// Global hooks are executables named after the event in $HOME/.prj/hooks,
+// project hooks are shell commands in the [hooks] table of the project's
+// .prj.toml manifest. As the manifest comes with the project, e.g. when it is
+// cloned, project hooks only run once the user allowed that manifest.
+package hooks
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/manifest"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Operations that hooks can be attached to. The event name is the operation
+// prefixed with pre- or post-, e.g. pre-delete.
+const (
+	New     = "new"
+	Add     = "add"
+	Delete  = "delete"
+	Goto    = "goto"
+	Archive = "archive"
+)
+
+// Pre returns the name of the event fired before operation.
+func Pre(operation string) string {
+	return "pre-" + operation
+}
+
+// Post returns the name of the event fired after operation.
+func Post(operation string) string {
+	return "post-" + operation
+}
+
+// Project describes the project an event is fired for.
+type Project struct {
+	Name       string
+	Path       string
+	Categories []string
+	BaseDir    string
+	// Command is the prj command that fired the event, e.g. clone for a
+	// new event.
+	Command string
+	// AllowedManifest is the hash of the manifest the user allowed hooks
+	// for, see manifest.Manifest.Hash. Project hooks don't run when it is
+	// empty or the manifest changed since.
+	AllowedManifest string
+}
+
+func (p Project) env(event string) []string {
+	return []string{
+		"PRJ_EVENT=" + event,
+		"PRJ_COMMAND=" + p.Command,
+		"PRJ_NAME=" + p.Name,
+		"PRJ_PATH=" + p.Path,
+		"PRJ_CATEGORIES=" + strings.Join(p.Categories, "/"),
+		"PRJ_BASEDIR=" + p.BaseDir,
+	}
+}
+
+// Runner fires events. Output of the hooks is written to Stdout and Stderr.
+type Runner struct {
+	Dir    string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Run executes the global hook and the project hooks for event in that
+// order. It stops at and returns the first failure. Project hooks that are
+// not allowed are skipped with a notice on Stderr.
+func (r Runner) Run(event string, p Project) error {
+	// Hooks run in the project directory, or the base dir when the project
+	// directory does not exist (yet).
+	dir := p.Path
+	if exists, _ := isDir(dir); !exists {
+		dir = p.BaseDir
+		if exists, _ := isDir(dir); !exists {
+			dir = ""
+		}
+	}
+
+	global := filepath.Join(r.Dir, event)
+	if stat, err := os.Stat(global); err == nil && !stat.IsDir() {
+		if runtime.GOOS != "windows" && stat.Mode().Perm()&0111 == 0 {
+			return fmt.Errorf("hook %s is not executable", global)
+		}
+		if err := r.exec(exec.Command(global), dir, event, p); err != nil {
+			return fmt.Errorf("hook %s failed: %s", global, err.Error())
+		}
+	}
+
+	if exists, _ := isDir(p.Path); !exists {
+		return nil
+	}
+	// A manifest that can't be read can't have been allowed either, so it
+	// doesn't stop the operation.
+	m, err := manifest.Load(p.Path)
+	if err != nil {
+		fmt.Fprintf(r.Stderr, "skipping the %s hooks of %s: %s\n", event, p.Name, err.Error())
+		return nil
+	}
+	if len(m.Hooks[event]) == 0 {
+		return nil
+	}
+	if m.Hash != p.AllowedManifest {
+		reason := "are not allowed"
+		if p.AllowedManifest != "" {
+			reason = "changed since they were allowed"
+		}
+		fmt.Fprintf(r.Stderr, "skipping the %s hooks of %s, they %s. Review %s and run 'prj hooks allow %s' to run them\n",
+			event, p.Name, reason, filepath.Join(p.Path, manifest.FileName), p.Name)
+		return nil
+	}
+	for _, command := range m.Hooks[event] {
+		if err := r.exec(shellCommand(command), dir, event, p); err != nil {
+			return fmt.Errorf("%s hook '%s' of %s failed: %s", event, command, p.Name, err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (r Runner) exec(cmd *exec.Cmd, dir string, event string, p Project) error {
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), p.env(event)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = r.Stdout
+	cmd.Stderr = r.Stderr
+	return cmd.Run()
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+func isDir(path string) (bool, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return stat.IsDir(), nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/fsutil"
+	"github.com/Tebro/prj/manifest"
+	"github.com/Tebro/prj/vcs"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileRecord is a file inside a project, Path is relative to the project.
+type fileRecord struct {
+	Path     string
+	Modified time.Time
+}
+
+// infoRecord is everything 'prj info' knows about a project. Facts that
+// could not be determined, e.g. the branch of a project without version
+// control, are left empty.
+type infoRecord struct {
+	Name         string
+	Path         string
+	Exists       bool
+	Categories   []string
+	Tags         []string
+	Description  string
+	Archived     bool
+	Size         int64
+	Files        int
+	LastModified *fileRecord
+	VCS          string
+	Branch       string
+	Remotes      []vcs.Remote
+	LastCommit   *vcs.Commit
+	Languages    []string
+	BuildSystems []string
+	Detected     *time.Time
+	Manifest     map[string]interface{}
+	Created      *time.Time
+	Accessed     *time.Time
+	AccessCount  int
+	Fingerprint  *db.Fingerprint
+}
+
+// walkStats returns the total size and number of files in dir and the most
+// recently modified file outside the version control metadata.
+func walkStats(dir string) (int64, int, *fileRecord) {
+	var size int64
+	var files int
+	var last *fileRecord
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return nil
+		}
+		size += info.Size()
+		files++
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || fsutil.IsVCSMetadata(strings.Split(filepath.ToSlash(rel), "/")[0]) {
+			return nil
+		}
+		if last == nil || info.ModTime().After(last.Modified) {
+			last = &fileRecord{Path: filepath.ToSlash(rel), Modified: info.ModTime()}
+		}
+		return nil
+	})
+	return size, files, last
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func newInfoRecord(c *cli.Context, p db.Project) (infoRecord, error) {
+	r := infoRecord{
+		Name:        p.Name,
+		Path:        p.Path,
+		Categories:  getProjectCategories(c, p),
+		Tags:        p.Tags,
+		Description: p.Description,
+		Archived:    p.Archived,
+		Created:     timeOrNil(p.Created),
+		Accessed:    timeOrNil(p.Accessed),
+		AccessCount: p.AccessCount,
+		Fingerprint: p.Fingerprint,
+	}
+
+	// Lists are empty rather than null in JSON, like in 'prj ls'.
+	r.Categories = append([]string{}, r.Categories...)
+	r.Tags = append([]string{}, r.Tags...)
+	r.Remotes = []vcs.Remote{}
+	r.Languages = []string{}
+	r.BuildSystems = []string{}
+
+	if isDir, _ := pathIsDir(p.Path); !isDir {
+		return r, nil
+	}
+	r.Exists = true
+	r.Size, r.Files, r.LastModified = walkStats(p.Path)
+
+	if v, ok := vcs.Detect(p.Path); ok {
+		r.VCS = v.Name()
+		r.Branch, _ = v.CurrentBranch(p.Path)
+		if remotes, err := v.Remotes(p.Path); err == nil && remotes != nil {
+			r.Remotes = remotes
+		}
+		if commit, err := v.LastCommit(p.Path); err == nil {
+			r.LastCommit = &commit
+		}
+	}
+
+	if p.Detection != nil {
+		r.Languages = append(r.Languages, p.Detection.Languages...)
+		r.BuildSystems = append(r.BuildSystems, p.Detection.BuildSystems...)
+		r.Detected = timeOrNil(p.Detection.Refreshed)
+	}
+
+	m, err := manifest.Load(p.Path)
+	if err != nil {
+		return r, err
+	}
+	if len(m.Raw) > 0 {
+		r.Manifest = m.Raw
+	}
+	return r, nil
+}
+
+// formatSize formats a number of bytes with a binary unit, e.g. 1.5 MiB.
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// manifestLines flattens a decoded manifest into sorted key = value lines.
+func manifestLines(prefix string, doc map[string]interface{}) []string {
+	var keys []string
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var lines []string
+	for _, key := range keys {
+		switch value := doc[key].(type) {
+		case map[string]interface{}:
+			lines = append(lines, manifestLines(prefix+key+".", value)...)
+		case []map[string]interface{}:
+			for i, table := range value {
+				lines = append(lines, manifestLines(fmt.Sprintf("%s%s[%d].", prefix, key, i), table)...)
+			}
+		case string:
+			lines = append(lines, fmt.Sprintf("%s%s = %q", prefix, key, value))
+		default:
+			lines = append(lines, fmt.Sprintf("%s%s = %v", prefix, key, value))
+		}
+	}
+	return lines
+}
+
+const infoTimeLayout = "2006-01-02 15:04"
+
+func printInfo(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return exitErrorWrapper("invalid number of arguments, expected 0 or 1")
+	}
+
+	var p db.Project
+	var err error
+	if c.NArg() == 1 {
+		if p, err = db.GetProject(c.Args()[0]); err != nil {
+			return exitErrorWrapper("could not show project: %s", err.Error())
+		}
+	} else if p, _, err = currentProject(""); err != nil {
+		return err
+	}
+
+	p = detectProjects([]db.Project{p}, false)[0]
+	r, err := newInfoRecord(c, p)
+	if err != nil {
+		return exitErrorWrapper("could not read project manifest: %s", err.Error())
+	}
+
+	if c.String("format") != "" {
+		return writeFormattedRecord(c, r)
+	}
+
+	log(c, "Name: %s", r.Name)
+	log(c, "Path: %s", r.Path)
+	if !r.Exists {
+		log(c, "Missing: the project directory does not exist")
+	}
+	if len(r.Categories) > 0 {
+		log(c, "Categories: %s", strings.Join(r.Categories, "/"))
+	}
+	if len(r.Tags) > 0 {
+		log(c, "Tags: %s", strings.Join(r.Tags, ", "))
+	}
+	if r.Description != "" {
+		log(c, "Description: %s", r.Description)
+	}
+	if r.Archived {
+		log(c, "Archived: true")
+	}
+	if r.Exists {
+		log(c, "Size: %s in %d files", formatSize(r.Size), r.Files)
+	}
+	if r.LastModified != nil {
+		log(c, "Last modified: %s (%s)", r.LastModified.Path, r.LastModified.Modified.Format(infoTimeLayout))
+	}
+
+	if r.VCS != "" {
+		log(c, "VCS: %s", r.VCS)
+	}
+	if r.Branch != "" {
+		log(c, "Branch: %s", r.Branch)
+	}
+	if len(r.Remotes) > 0 {
+		log(c, "Remotes:")
+		for _, remote := range r.Remotes {
+			log(c, "  %s %s", remote.Name, remote.URL)
+		}
+	}
+	if r.LastCommit != nil {
+		log(c, "Last commit: %s %s", r.LastCommit.Hash, r.LastCommit.Subject)
+		log(c, "  by %s <%s> at %s", r.LastCommit.Author, r.LastCommit.Email, r.LastCommit.Date.Format(infoTimeLayout))
+	}
+
+	if len(r.Languages) > 0 {
+		log(c, "Languages: %s", strings.Join(r.Languages, ", "))
+	}
+	if len(r.BuildSystems) > 0 {
+		log(c, "Build systems: %s", strings.Join(r.BuildSystems, ", "))
+	}
+	if r.Manifest != nil {
+		log(c, "Manifest (%s):", manifest.FileName)
+		for _, line := range manifestLines("", r.Manifest) {
+			log(c, "  %s", line)
+		}
+	}
+
+	if r.Created != nil {
+		log(c, "Registered: %s", r.Created.Format(infoTimeLayout))
+	}
+	if r.Accessed != nil {
+		log(c, "Last visited: %s", r.Accessed.Format(infoTimeLayout))
+		log(c, "Visits: %d", r.AccessCount)
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Package license bundles the texts of common open source licenses.
+package license
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// License is a bundled license text.
+type License struct {
+	ID   string
+	Name string
+	text string
+}
+
+// Render returns the license text with the copyright holder and year filled
+// in where the license has a place for them.
+func (l License) Render(author string, year int) string {
+	replacer := strings.NewReplacer(
+		"[year]", fmt.Sprint(year),
+		"[fullname]", author,
+		"[yyyy]", fmt.Sprint(year),
+		"[name of copyright owner]", author,
+	)
+	return replacer.Replace(l.text)
+}
+
+var licenses = []License{
+	{ID: "mit", Name: "MIT License", text: mitText},
+	{ID: "apache-2.0", Name: "Apache License 2.0", text: apache2Text},
+	{ID: "bsd-2-clause", Name: `BSD 2-Clause "Simplified" License`, text: bsd2Text},
+	{ID: "bsd-3-clause", Name: `BSD 3-Clause "New" or "Revised" License`, text: bsd3Text},
+	{ID: "gpl-2.0", Name: "GNU General Public License v2.0", text: gpl2Text},
+	{ID: "gpl-3.0", Name: "GNU General Public License v3.0", text: gpl3Text},
+	{ID: "lgpl-3.0", Name: "GNU Lesser General Public License v3.0", text: lgpl3Text},
+	{ID: "mpl-2.0", Name: "Mozilla Public License 2.0", text: mpl2Text},
+	{ID: "isc", Name: "ISC License", text: iscText},
+	{ID: "unlicense", Name: "The Unlicense", text: unlicenseText},
+}
+
+var aliases = map[string]string{
+	"apache":  "apache-2.0",
+	"apache2": "apache-2.0",
+	"bsd-2":   "bsd-2-clause",
+	"bsd2":    "bsd-2-clause",
+	"bsd-3":   "bsd-3-clause",
+	"bsd3":    "bsd-3-clause",
+	"gpl-2":   "gpl-2.0",
+	"gpl2":    "gpl-2.0",
+	"gpl-3":   "gpl-3.0",
+	"gpl3":    "gpl-3.0",
+	"gpl":     "gpl-3.0",
+	"lgpl-3":  "lgpl-3.0",
+	"lgpl":    "lgpl-3.0",
+	"mpl-2":   "mpl-2.0",
+	"mpl":     "mpl-2.0",
+}
+
+// Get returns the license with the given SPDX style id, e.g. mit or
+// apache-2.0. Common short forms like bsd-3 are accepted.
+func Get(id string) (License, error) {
+	id = strings.ToLower(id)
+	if alias, ok := aliases[id]; ok {
+		id = alias
+	}
+	for _, l := range licenses {
+		if l.ID == id {
+			return l, nil
+		}
+	}
+	return License{}, fmt.Errorf("unknown license '%s', available: %s", id, strings.Join(IDs(), ", "))
+}
+
+// All returns all bundled licenses sorted by id.
+func All() []License {
+	all := make([]License, len(licenses))
+	copy(all, licenses)
+	sort.Slice(all, func(a int, b int) bool {
+		return all[a].ID < all[b].ID
+	})
+	return all
+}
+
+// IDs returns the ids of all bundled licenses.
+func IDs() []string {
+	var ids []string
+	for _, l := range All() {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+const mitText = `MIT License
+
+Copyright (c) [year] [fullname]
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction, including without limitation the rights
+to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+copies of the Software, and to permit persons to whom the Software is
+furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+SOFTWARE.
+`
+
+const bsd2Text = `BSD 2-Clause License
+
+Copyright (c) [year], [fullname]
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+`
+
+const bsd3Text = `BSD 3-Clause License
+
+Copyright (c) [year], [fullname]
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products derived from
+   this software without specific prior written permission.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+`
+
+const iscText = `ISC License
+
+Copyright (c) [year], [fullname]
+
+Permission to use, copy, modify, and/or distribute this software for any
+purpose with or without fee is hereby granted, provided that the above
+copyright notice and this permission notice appear in all copies.
+
+THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+`
+
+const unlicenseText = `This is free and unencumbered software released into the public domain.
+
+Anyone is free to copy, modify, publish, use, compile, sell, or
+distribute this software, either in source code form or as a compiled
+binary, for any purpose, commercial or non-commercial, and by any
+means.
+
+In jurisdictions that recognize copyright laws, the author or authors
+of this software dedicate any and all copyright interest in the
+software to the public domain. We make this dedication for the benefit
+of the public at large and to the detriment of our heirs and
+successors. We intend this dedication to be an overt act of
+relinquishment in perpetuity of all present and future rights to this
+software under copyright law.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND,
+EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF
+MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT.
+IN NO EVENT SHALL THE AUTHORS BE LIABLE FOR ANY CLAIM, DAMAGES OR
+OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE,
+ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR
+OTHER DEALINGS IN THE SOFTWARE.
+
+For more information, please refer to <https://unlicense.org>
+`
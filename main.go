@@ -3,11 +3,15 @@ package main
 import (
 	"fmt"
 	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/fsutil"
+	"github.com/Tebro/prj/hooks"
+	"github.com/Tebro/prj/templates"
+	"github.com/Tebro/prj/vcs"
 	"gopkg.in/urfave/cli.v1"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 func main() {
@@ -34,6 +38,7 @@ func main() {
 					Name:    "list",
 					Aliases: []string{"l", "ls"},
 					Usage:   "Lists all configuration options",
+					Flags:   formatFlags(),
 					Action:  listConfig,
 				},
 				{
@@ -44,12 +49,59 @@ func main() {
 				},
 			},
 		},
+		{
+			Name:  "template",
+			Usage: "manage project templates",
+			Subcommands: []cli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"l", "ls"},
+					Usage:   "Lists available templates",
+					Flags:   formatFlags(),
+					Action:  listTemplates,
+				},
+				{
+					Name:      "save",
+					Usage:     "Save an existing project as a new template",
+					ArgsUsage: "[project] [template-name]",
+					Flags: []cli.Flag{
+						cli.StringSliceFlag{
+							Name:  "replace, r",
+							Usage: "Parameterise a literal string as literal=Variable, e.g. --replace myproject=Name. Variable names are letters, digits and _",
+						},
+						cli.StringFlag{
+							Name:  "description, d",
+							Usage: "Description stored in the template manifest",
+						},
+					},
+					Action: saveTemplate,
+				},
+			},
+		},
+		{
+			Name:  "hooks",
+			Usage: "manage which projects may run the hooks in their .prj.toml",
+			Subcommands: []cli.Command{
+				{
+					Name:      "allow",
+					Usage:     "Run the hooks in the current .prj.toml of a project, until it changes",
+					ArgsUsage: "[project]",
+					Action:    allowHooks,
+				},
+				{
+					Name:      "deny",
+					Usage:     "Stop running the hooks in the .prj.toml of a project",
+					ArgsUsage: "[project]",
+					Action:    denyHooks,
+				},
+			},
+		},
 		{
 			Name:      "new",
 			Aliases:   []string{"n"},
 			Usage:     "Create a new project",
 			ArgsUsage: "[name]",
-			Flags: []cli.Flag{
+			Flags: append([]cli.Flag{
 				cli.StringSliceFlag{
 					Name:  "categories, c",
 					Usage: "Optional organising levels, each category gets created in between the base dir and actual project dir",
@@ -58,18 +110,189 @@ func main() {
 					Name:  "git, g",
 					Usage: "Create a git repository",
 				},
+				cli.StringFlag{
+					Name:  "vcs",
+					Usage: "Create a repository of this version control system (" + strings.Join(vcs.Names(), ", ") + ")",
+				},
+				cli.StringFlag{
+					Name:  "branch",
+					Usage: "Name of the initial branch (overrides the DefaultBranch option), implies --git",
+				},
+				cli.BoolFlag{
+					Name:  "initial-commit",
+					Usage: "Commit the initial project contents, implies --git",
+				},
+				cli.StringSliceFlag{
+					Name:  "gitignore",
+					Usage: "Generate a .gitignore from bundled presets, e.g. --gitignore go,node, implies --git",
+				},
+				cli.StringFlag{
+					Name:  "remote",
+					Usage: "Add this url as the origin remote, implies --git",
+				},
 				cli.StringFlag{
 					Name:  "name, n",
 					Usage: "Explicitly set name of project for database (when names might otherwise clash)",
 				},
+				cli.StringFlag{
+					Name:  "template, t",
+					Usage: "Create the project from a template stored in $HOME/.prj/templates",
+				},
+				cli.StringSliceFlag{
+					Name:  "var",
+					Usage: "Set a template variable as key=value instead of being prompted for it",
+				},
+				cli.BoolFlag{
+					Name:  "no-input",
+					Usage: "Never prompt for template variables, use their defaults",
+				},
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "Start as a copy of a project, directory or archive (.tar, .tar.gz, .tgz, .zip)",
+				},
+				cli.StringFlag{
+					Name:  "license, l",
+					Usage: "Add a LICENSE file, see 'prj license' for the available licenses",
+				},
+				cli.BoolFlag{
+					Name:  "readme",
+					Usage: "Generate a README.md skeleton",
+				},
+				cli.StringFlag{
+					Name:  "description, d",
+					Usage: "Description of the project, used for the README (implies --readme)",
+				},
+			}, copyFlags()...),
+			Action: registering(createNew),
+		},
+		{
+			Name:      "clone",
+			Usage:     "Clone a repository into BaseDir, placing it in categories derived from the url",
+			ArgsUsage: "[url]",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "name, n",
+					Usage: "Explicitly set name of project for database (defaults to the repository name)",
+				},
+				cli.StringSliceFlag{
+					Name:  "categories, c",
+					Usage: "Use these categories instead of the ones derived from the CloneLayout option",
+				},
+				cli.IntFlag{
+					Name:  "depth",
+					Usage: "Create a shallow clone with this many commits",
+				},
+				cli.StringFlag{
+					Name:  "branch",
+					Usage: "Check out this branch instead of the remote's default",
+				},
+				cli.StringFlag{
+					Name:  "vcs",
+					Value: "git",
+					Usage: "Version control system of the repository (" + strings.Join(vcs.Names(), ", ") + ")",
+				},
 			},
-			Action: createNew,
+			Action: registering(cloneProject),
+		},
+		{
+			Name:      "cp",
+			Aliases:   []string{"copy"},
+			Usage:     "Create a new project as a copy of a project, directory or archive",
+			ArgsUsage: "[source] [name]",
+			Flags: append([]cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "categories, c",
+					Usage: "Optional organising levels, each category gets created in between the base dir and actual project dir",
+				},
+				cli.StringFlag{
+					Name:  "name, n",
+					Usage: "Explicitly set name of project for database (when names might otherwise clash)",
+				},
+			}, copyFlags()...),
+			Action: registering(copyProject),
+		},
+		{
+			Name:      "license",
+			Usage:     "Add a license to an existing project, lists the available licenses without arguments",
+			ArgsUsage: "<[project] [license]>",
+			Flags: append([]cli.Flag{
+				cli.BoolFlag{
+					Name:  "force, f",
+					Usage: "Replace an existing LICENSE file",
+				},
+			}, formatFlags()...),
+			Action: setLicense,
 		},
 		{
 			Name:      "add",
 			Usage:     "Add existing directory to prj. If path is left out the current directory will be used.",
 			ArgsUsage: "[name] <[path]>",
-			Action:    addExisting,
+			Action:    registering(addExisting),
+		},
+		{
+			Name:      "scan",
+			Usage:     "Find existing repositories and projects and register them. Scans the base dir if no roots are given.",
+			ArgsUsage: "<[root]...>",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "depth",
+					Value: 4,
+					Usage: "How many directories deep to search below each root, 0 for no limit",
+				},
+				cli.StringSliceFlag{
+					Name:  "ignore, i",
+					Usage: "Do not descend into directories matching this pattern (node_modules and vendor are always ignored)",
+				},
+				cli.IntFlag{
+					Name:  "jobs, j",
+					Value: 8,
+					Usage: "Number of directories to read concurrently",
+				},
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only show what would be registered",
+				},
+				cli.BoolFlag{
+					Name:  "yes, y",
+					Usage: "Register without asking for confirmation",
+				},
+			},
+			Action: registering(scanProjects),
+		},
+		{
+			Name:  "doctor",
+			Usage: "Check the registered projects for missing paths, duplicates, nested projects and permission problems",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "fix",
+					Usage: "Fix broken entries: prune (remove them), repoint (search the base dirs for the directory) or interactive",
+				},
+				cli.IntFlag{
+					Name:  "depth",
+					Value: 4,
+					Usage: "How many directories deep to search below each base dir when re-pointing, 0 for no limit",
+				},
+			},
+			Action: runDoctor,
+		},
+		{
+			Name:  "relink",
+			Usage: "Find projects that were moved on disk by their fingerprint and update their paths. Searches for all missing projects if no names are given.",
+			Description: `The fingerprint of a project is the root commit of its repository, the inode of its
+   directory and a random id that prj writes to prj-id in the .git, .hg or .jj directory
+   of the project. Only a directory with the same id is relinked right away. One that
+   matches by inode or root commit alone is relinked after asking, as the inode of a
+   deleted directory can be reused by an unrelated one and every clone shares the
+   root commit.`,
+			ArgsUsage: "<[name]...>",
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "depth",
+					Value: 4,
+					Usage: "How many directories deep to search below each base dir, 0 for no limit",
+				},
+			},
+			Action: relinkProjects,
 		},
 		{
 			Name:      "delete",
@@ -84,6 +307,25 @@ func main() {
 			},
 			Action: removeProject,
 		},
+		{
+			Name:      "rename",
+			Usage:     "Change the name of a project, the directory stays where it is",
+			ArgsUsage: "[name] [new name]",
+			Action:    renameProject,
+		},
+		{
+			Name:      "mv",
+			Aliases:   []string{"move"},
+			Usage:     "Move a project directory within the base dir",
+			ArgsUsage: "[name] <[new path]>",
+			Flags: []cli.Flag{
+				cli.StringSliceFlag{
+					Name:  "categories, c",
+					Usage: "Move the project to these categories, keeping its directory name",
+				},
+			},
+			Action: moveProject,
+		},
 		{
 			Name:      "goto",
 			Aliases:   []string{"g"},
@@ -104,10 +346,237 @@ func main() {
 			},
 		},
 		{
-			Name:    "list",
-			Aliases: []string{"l", "ls"},
-			Usage:   "Prints your projects with their respective paths",
-			Action:  listProjects,
+			Name:      "which",
+			Usage:     "Print the name of the project containing a path, the current directory by default",
+			ArgsUsage: "<[path]>",
+			Action:    printWhich,
+		},
+		{
+			Name:   "here",
+			Usage:  "Print the project containing the current directory, its root and the path within it",
+			Flags:  formatFlags(),
+			Action: printHere,
+		},
+		{
+			Name:      "info",
+			Usage:     "Show a detailed report of a project, the one containing the current directory by default",
+			ArgsUsage: "<[name]>",
+			Flags:     formatFlags(),
+			Action:    printInfo,
+		},
+		{
+			Name:      "du",
+			Usage:     "Show the disk usage of a project, your projects or the projects selected by a query, with totals",
+			ArgsUsage: "<[name|query]>",
+			Flags: append(append(filterFlags(),
+				cli.StringFlag{
+					Name:  "sort",
+					Value: "size",
+					Usage: "Sort by " + strings.Join(duSortKeys, ", "),
+				},
+				cli.BoolFlag{
+					Name:  "reverse",
+					Usage: "Reverse the sort order",
+				},
+				cli.IntFlag{
+					Name:  "limit",
+					Usage: "Show at most this many projects, or categories with --by-category",
+				},
+				cli.BoolFlag{
+					Name:  "by-category",
+					Usage: "Show the totals of every category instead of every project",
+				},
+				cli.BoolFlag{
+					Name:  "refresh",
+					Usage: "Measure everything again instead of using the cached sizes",
+				}),
+				formatFlags()...),
+			Action: diskUsage,
+		},
+		{
+			Name:      "clean",
+			Usage:     "Remove the build artefacts, like node_modules or target, of a project or the projects selected by a query",
+			ArgsUsage: "<[name|query]>",
+			Flags: append(selectorFlags(),
+				cli.BoolFlag{
+					Name:  "dry-run",
+					Usage: "Only show what would be removed and how much space it would free",
+				},
+				cli.BoolFlag{
+					Name:  "yes, y",
+					Usage: "Remove without asking for confirmation",
+				},
+				cli.BoolFlag{
+					Name:  "force",
+					Usage: "Also remove the artefacts of projects without .git or .gitignore, which can't be checked against it",
+				}),
+			Action: cleanProjects,
+		},
+		{
+			Name:      "status",
+			Aliases:   []string{"st"},
+			Usage:     "Show the branch, uncommitted changes, unpushed commits and stashes of a project or the projects selected by a query, exiting with 1 if any need attention",
+			ArgsUsage: "<[name|query]>",
+			Flags: append(append(selectorFlags(),
+				cli.IntFlag{
+					Name:  "jobs, j",
+					Value: 8,
+					Usage: "Number of projects to inspect concurrently",
+				},
+				cli.BoolFlag{
+					Name:  "only-dirty",
+					Usage: "Only show projects with uncommitted changes",
+				}),
+				formatFlags()...),
+			Action: showStatus,
+		},
+		{
+			Name:      "exec",
+			Usage:     "Run a command in the directory of every project or the projects selected by a query",
+			ArgsUsage: "<[name|query]> -- [command...]",
+			Flags: append(selectorFlags(),
+				cli.IntFlag{
+					Name:  "parallel, p",
+					Value: 1,
+					Usage: "Number of projects to run the command in at the same time",
+				},
+				cli.BoolFlag{
+					Name:  "group",
+					Usage: "Write the output of every project in one piece once the command is done, instead of prefixing every line",
+				},
+				cli.BoolFlag{
+					Name:  "fail-fast",
+					Usage: "Don't start the command in more projects once it failed",
+				}),
+			Action: execCommand,
+		},
+		{
+			Name:      "sync",
+			Usage:     "Fetch the remotes of every git project or the projects selected by a query and fast-forward the branches that are clean and behind their upstream",
+			ArgsUsage: "<[name|query]>",
+			Flags: append(append(selectorFlags(),
+				cli.IntFlag{
+					Name:  "jobs, j",
+					Value: 8,
+					Usage: "Number of projects to sync concurrently",
+				},
+				cli.DurationFlag{
+					Name:  "timeout",
+					Value: 2 * time.Minute,
+					Usage: "Give up on a project when fetching and fast-forwarding it takes longer, 0 for no limit",
+				}),
+				formatFlags()...),
+			Action: syncProjects,
+		},
+		{
+			Name:      "detect",
+			Usage:     "Detect the languages and build systems of a project or the projects selected by a query, applying the AutoTag rules",
+			ArgsUsage: "<[name|query]>",
+			Flags:     selectorFlags(),
+			Action:    detectCommand,
+		},
+		{
+			Name:      "list",
+			Aliases:   []string{"l", "ls"},
+			Usage:     "Prints your projects with their respective paths, optionally only those selected by a query like 'tag:go and dirty' or @saved",
+			ArgsUsage: "<[query]>",
+			Flags: append(append(selectorFlags(),
+				cli.BoolFlag{
+					Name:  "tree, t",
+					Usage: "Show the projects in a tree of their categories",
+				},
+				cli.IntFlag{
+					Name:  "depth",
+					Usage: "Number of category levels to expand in the tree, 0 for no limit",
+				},
+				cli.StringFlag{
+					Name:  "style",
+					Value: "unicode",
+					Usage: "How to draw the tree: unicode or ascii",
+				},
+			), formatFlags()...),
+			Action: listProjects,
+		},
+		{
+			Name:  "query",
+			Usage: "manage saved queries, used as @name wherever a query is accepted",
+			Subcommands: []cli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"l", "ls"},
+					Usage:   "Lists saved queries",
+					Flags:   formatFlags(),
+					Action:  listQueries,
+				},
+				{
+					Name:      "save",
+					Usage:     "Save a query under a name",
+					ArgsUsage: "[name] [query]",
+					Action:    saveQuery,
+				},
+				{
+					Name:      "delete",
+					Aliases:   []string{"rm"},
+					Usage:     "Delete a saved query",
+					ArgsUsage: "[name]",
+					Action:    deleteQuery,
+				},
+			},
+		},
+		{
+			Name:      "tag",
+			Usage:     "Add tags to a project, lists its tags if none are given",
+			ArgsUsage: "[name] <[tag]...>",
+			Flags: []cli.Flag{
+				cli.BoolFlag{
+					Name:  "delete, d",
+					Usage: "Remove the tags instead",
+				},
+			},
+			Action: tagProject,
+		},
+		{
+			Name:  "category",
+			Usage: "manage categories",
+			Subcommands: []cli.Command{
+				{
+					Name:    "list",
+					Aliases: []string{"l", "ls"},
+					Usage:   "Lists all categories with the number of projects in them",
+					Flags: append([]cli.Flag{
+						cli.BoolFlag{
+							Name:  "all, a",
+							Usage: "Include archived projects",
+						},
+					}, formatFlags()...),
+					Action: listCategories,
+				},
+				{
+					Name:      "rename",
+					Usage:     "Rename a category, moving its directory and updating all projects in it",
+					ArgsUsage: "[category] [new category]",
+					Action:    renameCategory,
+				},
+				{
+					Name:      "mv",
+					Aliases:   []string{"move"},
+					Usage:     "Move a category into another category, / moves it to the top level",
+					ArgsUsage: "[category] [destination]",
+					Action:    moveCategory,
+				},
+			},
+		},
+		{
+			Name:      "archive",
+			Usage:     "Archive a project or the projects selected by a query, hiding them from the project list",
+			ArgsUsage: "[name|query]",
+			Action:    archiveProject,
+		},
+		{
+			Name:      "unarchive",
+			Usage:     "Restore an archived project or the archived projects selected by a query",
+			ArgsUsage: "[name|query]",
+			Action:    unarchiveProject,
 		},
 	}
 	err := app.Run(os.Args)
@@ -132,11 +601,15 @@ func getBaseDir(c *cli.Context) string {
 	return db.GetConfigBaseDir()
 }
 
-func getFinalPath(c *cli.Context) string {
+// getBaseDirs returns the base dir followed by the ExtraBaseDirs option.
+func getBaseDirs(c *cli.Context) []string {
+	return append([]string{getBaseDir(c)}, db.GetConfigExtraBaseDirs()...)
+}
+
+func getFinalPath(c *cli.Context, name string) string {
 	base := getBaseDir(c)
 	cats := c.StringSlice("categories")
 	catPath := strings.Join(cats, "/")
-	name := c.Args()[0]
 
 	return filepath.Join(base, catPath, name)
 }
@@ -152,18 +625,14 @@ func pathExists(path string) (bool, error) {
 	return true, err
 }
 
-func getProjectName(c *cli.Context) string {
+func getProjectName(c *cli.Context, name string) string {
 	if len(c.String("name")) > 0 {
 		return c.String("name")
 	}
-	return c.Args()[0]
+	return name
 
 }
 
-func shouldCreateGit(c *cli.Context) bool {
-	return c.Bool("git") || db.GetConfigAlwaysGit()
-}
-
 func createBaseDirIfNotExists(c *cli.Context) error {
 	path := db.GetConfigBaseDir()
 
@@ -189,11 +658,17 @@ func createNew(c *cli.Context) error {
 		return exitErrorWrapper("name is required")
 	}
 
+	return createProject(c, c.Args()[0], c.String("from"))
+}
+
+// createProject creates a project in the directory dirName below the base dir
+// and categories. If from is set the project starts as a copy of it.
+func createProject(c *cli.Context, dirName string, from string) error {
 	if err := createBaseDirIfNotExists(c); err != nil {
 		return exitErrorWrapper("could not find or create base dir : %s", err.Error())
 	}
 
-	finalPath := getFinalPath(c)
+	finalPath := getFinalPath(c, dirName)
 
 	exists, err := pathExists(finalPath)
 	if err != nil {
@@ -204,9 +679,69 @@ func createNew(c *cli.Context) error {
 		return exitErrorWrapper("path %s exits", finalPath)
 	}
 
-	projectName := getProjectName(c)
+	projectName := getProjectName(c, dirName)
+
+	repository, err := getNewVCS(c)
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+
+	projectLicense, err := getNewLicense(c)
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+
+	var source *copySource
+	if len(from) > 0 {
+		if len(c.String("template")) > 0 {
+			return exitErrorWrapper("--template and --from cannot be combined")
+		}
+		source, err = resolveCopySource(from, finalPath)
+		if err != nil {
+			return exitErrorWrapper("%s", err.Error())
+		}
+		defer source.Close()
 
-	err = db.AddProject(projectName, finalPath)
+		if c.Bool("reinit") && repository == nil && source.VCS != nil {
+			repository = source.VCS
+			if err := vcs.Available(repository); err != nil {
+				return exitErrorWrapper("%s", err.Error())
+			}
+		}
+		// The copy keeps the repository of the source, setting up another
+		// one on top of it would fail halfway.
+		if repository != nil && source.VCS != nil && !getCopyOptions(c).SkipVCS {
+			if c.Bool("git") || len(c.String("vcs")) > 0 || len(c.String("branch")) > 0 || usesGitOptions(c) {
+				return exitErrorWrapper("%s already has a %s repository, use --reinit to replace it or --no-vcs to leave it out", from, source.VCS.Name())
+			}
+			repository = nil
+		}
+	}
+	categories := splitCategories(c.StringSlice("categories"))
+	gitOpts, err := getGitOptions(c, categories)
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+
+	var tmpl *templates.Template
+	var values map[string]interface{}
+	if len(c.String("template")) > 0 {
+		tmpl, err = loadTemplate(c.String("template"))
+		if err != nil {
+			return exitErrorWrapper("could not load template: %s", err.Error())
+		}
+		values, err = resolveTemplateValues(c, tmpl, projectName, finalPath)
+		if err != nil {
+			return exitErrorWrapper("%s", err.Error())
+		}
+	}
+
+	hp := hookProject(c, projectName, finalPath, categories)
+	if err := runPreHook(c, hooks.New, hp); err != nil {
+		return err
+	}
+
+	err = db.AddProject(projectName, finalPath, categories)
 	if err != nil {
 		return err
 	}
@@ -216,23 +751,81 @@ func createNew(c *cli.Context) error {
 		return exitErrorWrapper("could not create project directory: %s", err.Error())
 	}
 
-	if shouldCreateGit(c) {
-		os.Chdir(finalPath)
-		cmd := exec.Command("git", "init")
-		err := cmd.Run()
+	if source != nil {
+		err = fsutil.CopyTree(source.Path, finalPath, getCopyOptions(c))
+		if err != nil {
+			os.RemoveAll(finalPath)
+			return exitErrorWrapper("could not copy %s: %s", from, err.Error())
+		}
+		log(c, "Copied %s", from)
+	}
+
+	if tmpl != nil {
+		err = tmpl.Render(finalPath, values)
+		if err != nil {
+			os.RemoveAll(finalPath)
+			db.DeleteProject(projectName)
+			return exitErrorWrapper("could not render template: %s", err.Error())
+		}
+		log(c, "Rendered template %s", tmpl.Name)
+	}
+
+	if len(c.String("description")) > 0 {
+		db.SetProjectDescription(projectName, c.String("description"))
+	}
+
+	if projectLicense != nil {
+		if err := writeLicense(finalPath, *projectLicense, false); err != nil {
+			os.RemoveAll(finalPath)
+			return exitErrorWrapper("could not add license: %s", err.Error())
+		}
+		log(c, "Added %s", projectLicense.Name)
+	}
+
+	if shouldCreateReadme(c) {
+		written, err := writeReadme(finalPath, projectName, c.String("description"), projectLicense)
+		if err != nil {
+			os.RemoveAll(finalPath)
+			return exitErrorWrapper("could not create README: %s", err.Error())
+		}
+		if written {
+			log(c, "Created README.md")
+		}
+	}
+
+	if tmpl != nil {
+		err = tmpl.RunPost(finalPath, values, templateEnv(projectName, finalPath), c.App.Writer)
+		if err != nil {
+			// The project exists at this point, keep it registered even
+			// though we exit with an error.
+			db.PrepareForShutdown()
+			return exitErrorWrapper("project created, but %s", err.Error())
+		}
+	}
+
+	if repository != nil {
+		summary, err := initRepository(repository, finalPath, gitOpts)
+		for _, line := range summary {
+			log(c, line)
+		}
 		if err != nil {
-			log(c, "Failed to create git repository. Error %s", err.Error())
-		} else {
-			log(c, "Created Git repository")
+			db.PrepareForShutdown()
+			return exitErrorWrapper("project created, but setting up %s failed: %s", repository.Name(), err.Error())
 		}
 	}
 
+	detectRegistered(projectName)
 	log(c, "Created project")
 
+	runPostHook(c, hooks.New, hp)
+
 	return nil
 }
 
 func listConfig(c *cli.Context) error {
+	if c.String("format") != "" {
+		return writeFormatted(c, db.GetConfigOptions())
+	}
 	log(c, db.GetConfigList())
 	return nil
 }
@@ -242,7 +835,10 @@ func setConfig(c *cli.Context) error {
 		return exitErrorWrapper("invalid number of arguments, expected 2")
 	}
 
-	db.SetConfigOption(c.Args()[0], c.Args()[1])
+	err := db.SetConfigOption(c.Args()[0], c.Args()[1])
+	if err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
 
 	return nil
 }
@@ -251,10 +847,26 @@ func printGoToCommand(c *cli.Context) error {
 	if c.NArg() != 1 {
 		return exitErrorWrapper("invalid number of arguments, expected 1")
 	}
-	path, err := db.GetProjectDir(c.Args()[0])
+	name := c.Args()[0]
+	path, err := db.GetProjectDir(name)
 	if err != nil {
 		return err
 	}
+	if exists, _ := pathExists(path); !exists {
+		path, exists = offerRelink(c, name, path)
+		if !exists {
+			return exitErrorWrapper("project directory is missing")
+		}
+	}
+
+	// Everything written to stdout is eval'ed, hook output goes to stderr.
+	project, _ := db.GetProject(name)
+	hp := hookProject(c, name, path, getProjectCategories(c, project))
+	if err := runHook(c, os.Stderr, hooks.Pre(hooks.Goto), hp); err != nil {
+		return exitErrorWrapper("aborted by %s hook: %s", hooks.Pre(hooks.Goto), err.Error())
+	}
+
+	db.TouchProject(name)
 	log(c, "cd %s;", path)
 	if c.Bool("editor") {
 		editor := os.Getenv("EDITOR")
@@ -267,14 +879,37 @@ func printGoToCommand(c *cli.Context) error {
 		log(c, format, editor, inBackground)
 	}
 
+	if err := runHook(c, os.Stderr, hooks.Post(hooks.Goto), hp); err != nil {
+		fmt.Fprintf(os.Stderr, "%s hook failed: %s\n", hooks.Post(hooks.Goto), err.Error())
+	}
+
 	return nil
 }
 
 func listProjects(c *cli.Context) error {
+	projects, err := selectProjects(c)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("tree") {
+		if c.String("format") != "" {
+			return exitErrorWrapper("--tree and --format can't be used together")
+		}
+		return printProjectTree(c, projects)
+	}
+	if c.String("format") != "" {
+		records := []projectRecord{}
+		for _, p := range projects {
+			records = append(records, newProjectRecord(c, p))
+		}
+		return writeFormatted(c, records)
+	}
+
 	msg := fmt.Sprintf(
 		`Projects
 --------
-%s`, db.ListProjects())
+%s`, db.FormatProjects(projects))
 
 	log(c, msg)
 	return nil
@@ -322,10 +957,18 @@ func addExisting(c *cli.Context) error {
 		return exitErrorWrapper("path '%s' is not a directory", path)
 	}
 
-	err = db.AddProject(name, path)
+	hp := hookProject(c, name, path, projectCategories(c, path))
+	if err := runPreHook(c, hooks.Add, hp); err != nil {
+		return err
+	}
+
+	err = db.AddProject(name, path, hp.Categories)
 	if err != nil {
 		return exitErrorWrapper("could not add project: %s", err)
 	}
+	detectRegistered(name)
+
+	runPostHook(c, hooks.Add, hp)
 
 	return nil
 }
@@ -336,10 +979,16 @@ func removeProject(c *cli.Context) error {
 	}
 
 	name := c.Args()[0]
-	path, err := db.GetProjectDir(name)
+	project, err := db.GetProject(name)
 	if err != nil {
 		return exitErrorWrapper("could not delete project: %s", err.Error())
 	}
+	path := project.Path
+
+	hp := hookProject(c, name, path, getProjectCategories(c, project))
+	if err := runPreHook(c, hooks.Delete, hp); err != nil {
+		return err
+	}
 
 	if c.Bool("nocache") {
 		log(c, "Removing directory: %s", path)
@@ -354,5 +1003,7 @@ func removeProject(c *cli.Context) error {
 	db.DeleteProject(name)
 	log(c, "Project: '%s' deleted", name)
 
+	runPostHook(c, hooks.Delete, hp)
+
 	return nil
 }
@@ -0,0 +1,75 @@
+// Package manifest reads the optional per-project .prj.toml file, which holds
+// settings that travel with the project directory.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/Tebro/prj/toml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the name of the manifest in the project root.
+const FileName = ".prj.toml"
+
+// Manifest is the decoded .prj.toml of a project.
+type Manifest struct {
+	// Hooks maps event names, e.g. post-new, to shell commands.
+	Hooks map[string][]string
+	// CleanExclude lists artefact directories, relative to the project root,
+	// that 'prj clean' must keep. Glob patterns are allowed.
+	CleanExclude []string
+	// Raw holds the complete decoded document.
+	Raw map[string]interface{}
+	// Hash is the SHA-256 of the file, empty if the project has no manifest.
+	// Hooks are only run while it matches the hash the user allowed.
+	Hash string
+}
+
+// Load reads the manifest of the project in dir. A missing manifest results
+// in an empty Manifest.
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{
+		Hooks: make(map[string][]string),
+		Raw:   make(map[string]interface{}),
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, FileName))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	m.Hash = hex.EncodeToString(sum[:])
+
+	doc, err := toml.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", FileName, err.Error())
+	}
+	m.Raw = doc
+
+	hooks := toml.Table(doc, "hooks")
+	for event := range hooks {
+		commands := toml.Strings(hooks, event)
+		if commands == nil {
+			return nil, fmt.Errorf("invalid %s: hook %s must be a string or an array of strings", FileName, event)
+		}
+		m.Hooks[event] = commands
+	}
+
+	clean := toml.Table(doc, "clean")
+	if _, ok := clean["exclude"]; ok {
+		m.CleanExclude = toml.Strings(clean, "exclude")
+		if m.CleanExclude == nil {
+			return nil, fmt.Errorf("invalid %s: clean.exclude must be a string or an array of strings", FileName)
+		}
+	}
+
+	return m, nil
+}
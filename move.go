@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/fsutil"
+	"github.com/Tebro/prj/query"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+func renameProject(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return exitErrorWrapper("invalid number of arguments, expected 2")
+	}
+
+	name, newName := c.Args()[0], c.Args()[1]
+	if err := db.RenameProject(name, newName); err != nil {
+		return exitErrorWrapper("could not rename project: %s", err.Error())
+	}
+
+	log(c, "Renamed project '%s' to '%s'", name, newName)
+	renameInQueries(c, name, newName)
+	return nil
+}
+
+// renameInQueries points the name:<name> terms of the saved queries at the new
+// name of a project and warns about patterns that matched the old name.
+func renameInQueries(c *cli.Context, name string, newName string) {
+	for _, q := range db.GetQueries() {
+		rewritten, stale, err := query.RenameProject(q.Query, name, newName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not check saved query @%s: %s\n", q.Name, err.Error())
+			continue
+		}
+		if rewritten != q.Query {
+			db.SaveQuery(q.Name, rewritten)
+			log(c, "Updated saved query @%s: %s", q.Name, rewritten)
+		}
+		for _, term := range stale {
+			fmt.Fprintf(os.Stderr, "saved query @%s matched '%s' with %s, check that it still selects the right projects\n", q.Name, name, term)
+		}
+	}
+}
+
+// updateProjectPath points the project at path, updating the categories and
+// fingerprint that depend on it.
+func updateProjectPath(c *cli.Context, name string, path string) {
+	db.SetProjectPath(name, path)
+	db.SetProjectCategories(name, projectCategories(c, path))
+	recordFingerprint(name, path)
+}
+
+// restoreProject puts back the path, categories and fingerprint of a project
+// after a failed operation.
+func restoreProject(p db.Project) {
+	db.SetProjectPath(p.Name, p.Path)
+	db.SetProjectCategories(p.Name, p.Categories)
+	if p.Fingerprint != nil {
+		db.SetProjectFingerprint(p.Name, *p.Fingerprint)
+	}
+}
+
+// moveDir renames src to dst, falling back to copying and removing src when
+// they are on different file systems.
+func moveDir(src string, dst string) error {
+	err := os.Rename(src, dst)
+	if linkErr, ok := err.(*os.LinkError); !ok || linkErr.Err != syscall.EXDEV {
+		return err
+	}
+
+	if err := fsutil.CopyTree(src, dst, fsutil.CopyOptions{}); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	if err := os.RemoveAll(src); err != nil {
+		// Put back what was already removed before giving up on the move.
+		fsutil.CopyTree(dst, src, fsutil.CopyOptions{})
+		os.RemoveAll(dst)
+		return err
+	}
+	return nil
+}
+
+// createParents creates the parent directories of path and returns the
+// topmost directory it created, so it can be removed again on rollback.
+func createParents(path string) (string, error) {
+	created := ""
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		exists, err := pathExists(dir)
+		if err != nil {
+			return "", err
+		}
+		if exists || dir == filepath.Dir(dir) {
+			break
+		}
+		created = dir
+	}
+	if created == "" {
+		return "", nil
+	}
+	return created, os.MkdirAll(filepath.Dir(path), 0755)
+}
+
+// removeEmptyParents removes the category directories above path that were
+// left empty, stopping at the base dir.
+func removeEmptyParents(c *cli.Context, path string) {
+	base := absPath(getBaseDir(c))
+	for dir := filepath.Dir(path); isBelow(dir, base); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// parseCategories splits categories given as a,b or a/b as well as -c a -c b
+// into their path segments, which have to name a directory below the base dir.
+func parseCategories(values []string) ([]string, error) {
+	var categories []string
+	for _, value := range values {
+		for _, category := range strings.Split(strings.Replace(filepath.ToSlash(value), ",", "/", -1), "/") {
+			if category == "" || category == "." || category == ".." {
+				return nil, fmt.Errorf("invalid category '%s'", value)
+			}
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+func getMoveTarget(c *cli.Context, project db.Project) (string, error) {
+	cats, err := parseCategories(c.StringSlice("categories"))
+	if err != nil {
+		return "", err
+	}
+	if c.NArg() == 2 {
+		if len(c.StringSlice("categories")) > 0 {
+			return "", fmt.Errorf("a new path and --categories can't be used together")
+		}
+		return absPath(c.Args()[1]), nil
+	}
+	if len(cats) == 0 {
+		return "", fmt.Errorf("expected a new path or --categories")
+	}
+	return filepath.Join(absPath(getBaseDir(c)), filepath.Join(cats...), filepath.Base(project.Path)), nil
+}
+
+func moveProject(c *cli.Context) error {
+	if c.NArg() < 1 || c.NArg() > 2 {
+		return exitErrorWrapper("invalid number of arguments, expected 1 or 2")
+	}
+
+	name := c.Args()[0]
+	project, err := db.GetProject(name)
+	if err != nil {
+		return exitErrorWrapper("could not move project: %s", err.Error())
+	}
+	src := absPath(project.Path)
+	if isDir, err := pathIsDir(src); err != nil || !isDir {
+		return exitErrorWrapper("project directory '%s' does not exist, see 'prj doctor'", src)
+	}
+
+	dst, err := getMoveTarget(c, project)
+	if err != nil {
+		return exitErrorWrapper("could not move project: %s", err.Error())
+	}
+	if dst == src {
+		return exitErrorWrapper("project '%s' is already at %s", name, dst)
+	}
+	if isBelow(dst, src) {
+		return exitErrorWrapper("can't move project '%s' into itself", name)
+	}
+
+	inside := false
+	for _, dir := range getBaseDirs(c) {
+		if isBelow(dst, absPath(dir)) {
+			inside = true
+			break
+		}
+	}
+	if !inside {
+		return exitErrorWrapper("'%s' is not inside the base dir", dst)
+	}
+
+	exists, err := pathExists(dst)
+	if err != nil {
+		return exitErrorWrapper("could not determine if path exists: %s", err.Error())
+	}
+	if exists {
+		return exitErrorWrapper("path '%s' already exists", dst)
+	}
+
+	created, err := createParents(dst)
+	if err != nil {
+		return exitErrorWrapper("could not create directory: %s", err.Error())
+	}
+	rollbackParents := func() {
+		if created != "" {
+			os.RemoveAll(created)
+		}
+	}
+
+	if err := moveDir(src, dst); err != nil {
+		rollbackParents()
+		return exitErrorWrapper("could not move project directory: %s", err.Error())
+	}
+
+	updateProjectPath(c, name, dst)
+	if err := db.Save(); err != nil {
+		restoreProject(project)
+		if moveErr := moveDir(dst, src); moveErr != nil {
+			return exitErrorWrapper("could not save database: %s, and could not move the project back from %s: %s", err.Error(), dst, moveErr.Error())
+		}
+		rollbackParents()
+		return exitErrorWrapper("could not save database, move undone: %s", err.Error())
+	}
+
+	removeEmptyParents(c, src)
+	log(c, "Moved project '%s' to %s", name, dst)
+	return nil
+}
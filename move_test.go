@@ -0,0 +1,31 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCategories(t *testing.T) {
+	tests := []struct {
+		values []string
+		want   []string
+	}{
+		{[]string{"a,b"}, []string{"a", "b"}},
+		{[]string{"a/b"}, []string{"a", "b"}},
+		{[]string{"a", "b/c"}, []string{"a", "b", "c"}},
+		{[]string{"a,b/c"}, []string{"a", "b", "c"}},
+		{nil, nil},
+	}
+	for _, test := range tests {
+		got, err := parseCategories(test.values)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseCategories(%q) = %q, %v, want %q", test.values, got, err, test.want)
+		}
+	}
+
+	for _, values := range [][]string{{"a,,b"}, {"/a"}, {"a/"}, {""}, {"."}, {"a/../b"}, {"a", ".."}} {
+		if _, err := parseCategories(values); err == nil {
+			t.Errorf("parseCategories(%q): expected an error", values)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/format"
+	"gopkg.in/urfave/cli.v1"
+	"strings"
+)
+
+// formatFlags are the flags of every command that lists something.
+func formatFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringFlag{
+			Name:  "format",
+			Usage: "Output format: " + strings.Join(format.Names, ", ") + " or a Go template like '{{.Name}}\\t{{.Path}}'",
+		},
+		cli.BoolFlag{
+			Name:  "no-header",
+			Usage: "Leave out the header line of table, csv and tsv output",
+		},
+	}
+}
+
+func formatOptions(c *cli.Context) format.Options {
+	return format.Options{Format: c.String("format"), NoHeader: c.Bool("no-header")}
+}
+
+// writeFormatted writes records, a slice of structs, in the format given with
+// --format.
+func writeFormatted(c *cli.Context, records interface{}) error {
+	if err := format.Write(c.App.Writer, records, formatOptions(c)); err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+	return nil
+}
+
+// writeFormattedRecord writes a single struct in the format given with
+// --format.
+func writeFormattedRecord(c *cli.Context, record interface{}) error {
+	if err := format.WriteRecord(c.App.Writer, record, formatOptions(c)); err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+	return nil
+}
+
+// projectRecord is how projects are shown by --format.
+type projectRecord struct {
+	Name         string
+	Path         string
+	Categories   []string
+	Tags         []string
+	Description  string
+	Archived     bool
+	Languages    []string
+	BuildSystems []string
+}
+
+func newProjectRecord(c *cli.Context, p db.Project) projectRecord {
+	categories := getProjectCategories(c, p)
+	if categories == nil {
+		categories = []string{}
+	}
+	tags := p.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	r := projectRecord{
+		Name:         p.Name,
+		Path:         p.Path,
+		Categories:   categories,
+		Tags:         tags,
+		Description:  p.Description,
+		Archived:     p.Archived,
+		Languages:    []string{},
+		BuildSystems: []string{},
+	}
+	if p.Detection != nil {
+		r.Languages = append(r.Languages, p.Detection.Languages...)
+		r.BuildSystems = append(r.BuildSystems, p.Detection.BuildSystems...)
+	}
+	return r
+}
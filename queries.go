@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/query"
+	"gopkg.in/urfave/cli.v1"
+	"regexp"
+	"strings"
+)
+
+var queryNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func saveQuery(c *cli.Context) error {
+	if c.NArg() < 2 {
+		return exitErrorWrapper("invalid number of arguments, expected a name and a query")
+	}
+
+	name := c.Args()[0]
+	if !queryNamePattern.MatchString(name) {
+		return exitErrorWrapper("invalid query name '%s', use letters, digits, '.', '-' and '_'", name)
+	}
+	expr := strings.Join(c.Args()[1:], " ")
+	if _, err := query.Parse(expr, db.GetQuery); err != nil {
+		return exitErrorWrapper("%s", err.Error())
+	}
+
+	db.SaveQuery(name, expr)
+	log(c, "Saved query '%s', use it with 'prj ls @%s'", name, name)
+	return nil
+}
+
+func listQueries(c *cli.Context) error {
+	queries := db.GetQueries()
+	if c.String("format") != "" {
+		if queries == nil {
+			queries = []db.SavedQuery{}
+		}
+		return writeFormatted(c, queries)
+	}
+
+	retval := ""
+	for _, q := range queries {
+		retval = fmt.Sprintf("%s%s: %s\n", retval, q.Name, q.Query)
+	}
+	log(c, `Queries
+-------
+%s`, retval)
+	return nil
+}
+
+func deleteQuery(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return exitErrorWrapper("invalid number of arguments, expected 1")
+	}
+	if err := db.DeleteQuery(c.Args()[0]); err != nil {
+		return exitErrorWrapper("could not delete query: %s", err.Error())
+	}
+	return nil
+}
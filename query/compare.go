@@ -0,0 +1,81 @@
+package query
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/selector"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var sizeUnits = map[string]int64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
+	"g": 1 << 30,
+	"t": 1 << 40,
+}
+
+var sizePattern = regexp.MustCompile(`^([0-9]+)([kmgt]?)b?$`)
+
+// parseSize parses sizes like 512, 100K, 20M or 1G.
+func parseSize(s string) (int64, error) {
+	m := sizePattern.FindStringSubmatch(strings.ToLower(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size '%s', expected a number optionally followed by K, M, G or T", s)
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%s': %s", s, err.Error())
+	}
+	return n * sizeUnits[m[2]], nil
+}
+
+// compareFilter compiles age, accessed and created comparisons, where more
+// means longer ago, and size comparisons.
+func compareFilter(key string, op string, value string) (selector.Filter, error) {
+	if key == "size" {
+		n, err := parseSize(value)
+		if err != nil {
+			return nil, err
+		}
+		return func(item *selector.Item) bool {
+			return compare(item.Size(), op, n)
+		}, nil
+	}
+
+	d, err := selector.ParseAge(value)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return func(item *selector.Item) bool {
+		var t time.Time
+		switch key {
+		case "age":
+			if !item.Exists() {
+				return false
+			}
+			t = item.Modified()
+		case "accessed":
+			t = item.Accessed
+		default:
+			t = item.Created
+		}
+		return compare(int64(now.Sub(t)), op, int64(d))
+	}, nil
+}
+
+func compare(a int64, op string, b int64) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	default:
+		return a <= b
+	}
+}
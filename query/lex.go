@@ -0,0 +1,142 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenWord
+)
+
+// token is a parenthesis or a word. Words are split into key, comparison
+// operator and value, words without an operator only have a key.
+type token struct {
+	kind   tokenKind
+	pos    int
+	text   string
+	key    string
+	op     string
+	value  string
+	quoted bool
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEnd:
+		return "end of query"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+func (t token) isKeyword(keyword string) bool {
+	return t.kind == tokenWord && t.op == "" && !t.quoted && strings.EqualFold(t.key, keyword)
+}
+
+// operators are tried in order, so the two character ones come first.
+var operators = []string{">=", "<=", ":", "=", ">", "<"}
+
+func isKeyChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '@' || r == '.'
+}
+
+func lex(query string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(query) {
+		c := query[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, pos: pos, text: "("})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, pos: pos, text: ")"})
+			pos++
+		default:
+			t, end, err := lexWord(query, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			pos = end
+		}
+	}
+	return append(tokens, token{kind: tokenEnd, pos: len(query)}), nil
+}
+
+// lexWord reads the word starting at start and returns it together with the
+// offset after it.
+func lexWord(query string, start int) (token, int, error) {
+	t := token{kind: tokenWord, pos: start}
+
+	pos := start
+	for pos < len(query) {
+		r := rune(query[pos])
+		if !isKeyChar(r) || r > unicode.MaxASCII {
+			break
+		}
+		pos++
+	}
+	t.key = query[start:pos]
+
+	for _, op := range operators {
+		if strings.HasPrefix(query[pos:], op) {
+			t.op = op
+			pos += len(op)
+			break
+		}
+	}
+
+	var value strings.Builder
+	for pos < len(query) {
+		c := query[pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '(' || c == ')' {
+			break
+		}
+		if c != '"' {
+			value.WriteByte(c)
+			pos++
+			continue
+		}
+
+		t.quoted = true
+		quote := pos
+		pos++
+		for {
+			if pos >= len(query) {
+				return t, pos, &SyntaxError{Query: query, Pos: quote, Msg: "unterminated quoted value"}
+			}
+			if query[pos] == '\\' && pos+1 < len(query) {
+				value.WriteByte(query[pos+1])
+				pos += 2
+				continue
+			}
+			if query[pos] == '"' {
+				pos++
+				break
+			}
+			value.WriteByte(query[pos])
+			pos++
+		}
+	}
+	t.text = query[start:pos]
+
+	if t.op == "" && value.Len() > 0 {
+		return t, pos, &SyntaxError{Query: query, Pos: start, Msg: fmt.Sprintf("unexpected '%s', expected a term like tag:go or age>60d", t.text)}
+	}
+	t.value = value.String()
+	return t, pos, nil
+}
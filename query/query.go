@@ -0,0 +1,310 @@
+// Package query parses expressions that select projects, such as
+//
+//	tag:go and category:work and (dirty or age>60d)
+//
+// into selector filters.
+//
+// Terms are combined with and, or and not, and grouped with parentheses.
+// Terms next to each other without an operator are combined with and. The
+// terms are
+//
+//	tag:<tag>  category:<a/b>  name:<glob>  path:<dir>  vcs:<name|none>
+//	lang:<language>  build:<build system>
+//	dirty  clean  missing  present  archived
+//	age>60d  accessed<1w  created>1y  size>100M
+//	@<saved query>
+//
+// age is the time since the project was last modified. The comparisons
+// accept >, <, >= and <=. Values containing spaces or parentheses can be
+// quoted with double quotes.
+package query
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/selector"
+	"path/filepath"
+	"strings"
+)
+
+// SyntaxError is returned for expressions that can't be parsed.
+type SyntaxError struct {
+	Query string
+	// Pos is the byte offset in Query where the problem was found.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at column %d\n  %s\n  %s^", e.Msg, e.Pos+1, e.Query, strings.Repeat(" ", e.Pos))
+}
+
+// Lookup returns the expression of a saved query.
+type Lookup func(name string) (string, bool)
+
+// Parse compiles expr into a filter. Saved queries referenced with @name are
+// resolved with lookup, which may be nil if there are none.
+func Parse(expr string, lookup Lookup) (selector.Filter, error) {
+	return parse(expr, lookup, nil)
+}
+
+func parse(expr string, lookup Lookup, seen []string) (selector.Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{query: expr, tokens: tokens, lookup: lookup, seen: seen}
+	if p.peek().kind == tokenEnd {
+		return nil, p.errorf(p.peek(), "empty query")
+	}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return f, nil
+}
+
+type parser struct {
+	query  string
+	tokens []token
+	pos    int
+	lookup Lookup
+	// seen holds the saved queries being expanded, to catch cycles.
+	seen []string
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEnd {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Query: p.query, Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// startsTerm reports whether t can begin an operand, for implicit and.
+func startsTerm(t token) bool {
+	return t.kind == tokenLParen || (t.kind == tokenWord && !t.isKeyword("and") && !t.isKeyword("or"))
+}
+
+func (p *parser) parseOr() (selector.Filter, error) {
+	f, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []selector.Filter{f}
+	for p.peek().isKeyword("or") {
+		p.next()
+		f, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return selector.Any(filters...), nil
+}
+
+func (p *parser) parseAnd() (selector.Filter, error) {
+	f, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	filters := []selector.Filter{f}
+	for {
+		if p.peek().isKeyword("and") {
+			p.next()
+		} else if !startsTerm(p.peek()) {
+			break
+		}
+		f, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return selector.All(filters...), nil
+}
+
+func (p *parser) parseNot() (selector.Filter, error) {
+	if p.peek().isKeyword("not") {
+		p.next()
+		f, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return selector.Not(f), nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (selector.Filter, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing, "expected ')' to close the '(' at column %d, found %s", t.pos+1, closing)
+		}
+		return f, nil
+	case tokenWord:
+		if t.isKeyword("and") || t.isKeyword("or") || t.isKeyword("not") {
+			return nil, p.errorf(t, "expected a term, found %s", t)
+		}
+		if strings.HasPrefix(t.text, "@") {
+			return p.saved(t)
+		}
+		return p.term(t)
+	default:
+		return nil, p.errorf(t, "expected a term, found %s", t)
+	}
+}
+
+// saved expands a reference to a saved query.
+func (p *parser) saved(t token) (selector.Filter, error) {
+	name := strings.TrimPrefix(t.text, "@")
+	for _, s := range p.seen {
+		if s == name {
+			return nil, p.errorf(t, "saved query '%s' refers to itself", name)
+		}
+	}
+	if p.lookup == nil {
+		return nil, p.errorf(t, "unknown saved query '%s'", name)
+	}
+	expr, ok := p.lookup(name)
+	if !ok {
+		return nil, p.errorf(t, "unknown saved query '%s'", name)
+	}
+
+	f, err := parse(expr, p.lookup, append(p.seen, name))
+	if err != nil {
+		return nil, fmt.Errorf("in saved query '%s': %s", name, err.Error())
+	}
+	return f, nil
+}
+
+var words = map[string]selector.Filter{
+	"dirty":    selector.Dirty,
+	"clean":    selector.Not(selector.Dirty),
+	"missing":  selector.Missing,
+	"present":  selector.Not(selector.Missing),
+	"archived": selector.Archived,
+}
+
+func (p *parser) term(t token) (selector.Filter, error) {
+	if t.op == "" {
+		if f, ok := words[strings.ToLower(t.key)]; ok && !t.quoted {
+			return f, nil
+		}
+		return nil, p.errorf(t, "unknown term %s, expected one of dirty, clean, missing, present, archived or key:value like tag:go", t)
+	}
+
+	key := strings.ToLower(t.key)
+	switch key {
+	case "tag", "category", "name", "path", "vcs", "lang", "build":
+		if t.op != ":" && t.op != "=" {
+			return nil, p.errorf(t, "%s can only be compared with ':'", key)
+		}
+		if t.value == "" {
+			return nil, p.errorf(t, "missing value for %s", key)
+		}
+		return textFilter(key, t.value)
+	case "age", "accessed", "created", "size":
+		if t.op == ":" || t.op == "=" {
+			return nil, p.errorf(t, "%s needs one of the comparisons >, <, >= or <=", key)
+		}
+		f, err := compareFilter(key, t.op, t.value)
+		if err != nil {
+			return nil, p.errorf(t, "%s", err.Error())
+		}
+		return f, nil
+	default:
+		return nil, p.errorf(t, "unknown key '%s', expected one of tag, category, name, path, vcs, lang, build, age, accessed, created or size", t.key)
+	}
+}
+
+func textFilter(key string, value string) (selector.Filter, error) {
+	switch key {
+	case "tag":
+		return selector.Tag(value), nil
+	case "category":
+		var category []string
+		for _, segment := range strings.Split(value, "/") {
+			if segment != "" {
+				category = append(category, segment)
+			}
+		}
+		return selector.Category(category), nil
+	case "name":
+		return selector.Name(value)
+	case "path":
+		abs, err := filepath.Abs(value)
+		if err != nil {
+			return nil, err
+		}
+		return selector.PathPrefix(abs), nil
+	case "lang":
+		return selector.Language(value), nil
+	case "build":
+		return selector.BuildSystem(value), nil
+	default:
+		return selector.VCS(value), nil
+	}
+}
+
+// UsesKeys reports whether expr, or a saved query it refers to, has a term
+// with one of keys, e.g. to find out if a filter needs data that is expensive
+// to collect. Invalid expressions use no keys, Parse reports them.
+func UsesKeys(expr string, lookup Lookup, keys ...string) bool {
+	return usesKeys(expr, lookup, keys, nil)
+}
+
+func usesKeys(expr string, lookup Lookup, keys []string, seen []string) bool {
+	tokens, err := lex(expr)
+	if err != nil {
+		return false
+	}
+	for _, t := range tokens {
+		if t.kind != tokenWord {
+			continue
+		}
+		if strings.HasPrefix(t.text, "@") && lookup != nil {
+			name := strings.TrimPrefix(t.text, "@")
+			saved, ok := lookup(name)
+			if ok && !contains(seen, name) && usesKeys(saved, lookup, keys, append(seen, name)) {
+				return true
+			}
+			continue
+		}
+		if t.op != "" && contains(keys, strings.ToLower(t.key)) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
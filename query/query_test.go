@@ -0,0 +1,180 @@
+package query
+
+import (
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/selector"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testItems are the projects the parsed filters are applied to. None of them
+// exists on disk, the tests only use terms that don't look at the directory.
+func testItems() []*selector.Item {
+	goDetection := &db.Detection{Languages: []string{"Go"}, BuildSystems: []string{"go"}}
+	return []*selector.Item{
+		selector.NewItem(db.Project{Name: "api", Tags: []string{"go", "work"}, Detection: goDetection}, []string{"work", "acme"}),
+		selector.NewItem(db.Project{Name: "web", Tags: []string{"js"}}, []string{"work"}),
+		selector.NewItem(db.Project{Name: "tool", Tags: []string{"go"}, Detection: goDetection}, []string{"personal"}),
+		selector.NewItem(db.Project{Name: "my app", Tags: []string{"two words"}, Archived: true}, []string{"work"}),
+	}
+}
+
+func selected(t *testing.T, expr string) []string {
+	t.Helper()
+	f, err := Parse(expr, savedQueries)
+	if err != nil {
+		t.Fatalf("Parse(%q): %s", expr, err)
+	}
+	names := []string{}
+	for _, item := range testItems() {
+		if f(item) {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		// and binds tighter than or, not tighter than and.
+		{"tag:go or tag:js and category:personal", []string{"api", "tool"}},
+		{"tag:js and category:work or category:personal", []string{"web", "tool"}},
+		{"not tag:go and category:work", []string{"web", "my app"}},
+		{"not tag:go or tag:work", []string{"api", "web", "my app"}},
+		{"not not tag:js", []string{"web"}},
+		// Parentheses group.
+		{"(tag:go or tag:js) and category:work", []string{"api", "web"}},
+		{"not (tag:go and category:work)", []string{"web", "tool", "my app"}},
+		{"((tag:js))", []string{"web"}},
+		// Terms next to each other are combined with and.
+		{"tag:go category:work", []string{"api"}},
+		{"tag:go (category:personal or name:api)", []string{"api", "tool"}},
+		// Keywords and keys ignore case.
+		{"TAG:go AND NOT name:tool", []string{"api"}},
+		{"category:work/acme", []string{"api"}},
+		{"name=a*", []string{"api"}},
+		{"lang:GO and build:go", []string{"api", "tool"}},
+		{"archived", []string{"my app"}},
+		// Quoting.
+		{`tag:"two words"`, []string{"my app"}},
+		{`name:"my app" or name:"w\eb"`, []string{"web", "my app"}},
+		{`name:"(not)"`, []string{}},
+		{`"not" tag:x`, nil},
+		// Saved queries.
+		{"@go", []string{"api", "tool"}},
+		{"@work", []string{"api", "web"}},
+		{"@both", []string{"api", "web", "tool"}},
+		{"@deep and not name:tool", []string{"api", "web"}},
+		{"@go or @go", []string{"api", "tool"}},
+	}
+
+	for _, test := range tests {
+		if test.want == nil {
+			if _, err := Parse(test.expr, savedQueries); err == nil {
+				t.Errorf("Parse(%q): expected an error", test.expr)
+			}
+			continue
+		}
+		if got := selected(t, test.expr); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q selected %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "empty query"},
+		{"   ", "empty query at column 4"},
+		{"(tag:go", "expected ')' to close the '(' at column 1, found end of query at column 8"},
+		{"tag:go or (tag:js", "expected ')' to close the '(' at column 11"},
+		{"tag:go)", "unexpected ')' at column 7"},
+		{"and tag:go", "expected a term, found 'and' at column 1"},
+		{"tag:go or", "expected a term, found end of query at column 10"},
+		{"tag:go and not", "expected a term, found end of query"},
+		{"()", "expected a term, found ')' at column 2"},
+		{"tag:go and foo", "unknown term 'foo', expected one of dirty, clean, missing, present, archived or key:value like tag:go at column 12"},
+		{"color:red", "unknown key 'color'"},
+		{"age:30d", "age needs one of the comparisons >, <, >= or <="},
+		{"tag>3", "tag can only be compared with ':'"},
+		{"tag:", "missing value for tag"},
+		{`tag:"go`, "unterminated quoted value at column 5"},
+		{"age>soon", "at column 1"},
+		{"size>lots", "invalid size 'lots'"},
+		{"name:[", "invalid name pattern"},
+		{"@nope", "unknown saved query 'nope' at column 1"},
+		{"@loop", "in saved query 'loop': saved query 'loop' refers to itself"},
+		{"tag:go or @bad", "in saved query 'bad': expected ')' to close the '(' at column 1"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.expr, savedQueries)
+		if err == nil {
+			t.Errorf("Parse(%q): expected an error containing %q", test.expr, test.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parse(%q): got error %q, want it to contain %q", test.expr, err.Error(), test.want)
+		}
+	}
+}
+
+func TestSyntaxErrorPointsAtColumn(t *testing.T) {
+	_, err := Parse("tag:go and foo", nil)
+	want := "unknown term 'foo', expected one of dirty, clean, missing, present, archived or key:value like tag:go at column 12\n  tag:go and foo\n             ^"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %q, want %q", err, want)
+	}
+}
+
+func TestParseWithoutLookup(t *testing.T) {
+	if _, err := Parse("@go", nil); err == nil || !strings.Contains(err.Error(), "unknown saved query 'go'") {
+		t.Errorf("got %v, want an unknown saved query error", err)
+	}
+}
+
+// savedQueries is the lookup used by the tests.
+func savedQueries(name string) (string, bool) {
+	saved := map[string]string{
+		"go":    "lang:go",
+		"work":  "category:work and not archived",
+		"both":  "@go or @work",
+		"loop":  "@loop and tag:x",
+		"bad":   "(tag:x",
+		"deep":  "@both",
+		"empty": "",
+	}
+	expr, ok := saved[name]
+	return expr, ok
+}
+
+func TestUsesKeys(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"lang:go", true},
+		{"tag:x and BUILD:npm", true},
+		{"tag:lang", false},
+		{"name:build", false},
+		{"dirty or age>30d", false},
+		{"@go", true},
+		{"@work", false},
+		{"@deep", true},
+		{"@loop", false},
+		{"@unknown", false},
+		{`lang:"go`, false},
+	}
+
+	for _, test := range tests {
+		if got := UsesKeys(test.expr, savedQueries, "lang", "build"); got != test.want {
+			t.Errorf("UsesKeys(%q) = %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package query
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// RenameProject rewrites the name:<old> terms of expr to refer to the project
+// by its new name. Glob patterns that match the old name but not the new one
+// can't be rewritten faithfully, they are kept and returned in stale so they
+// can be reported.
+func RenameProject(expr string, oldName string, newName string) (rewritten string, stale []string, err error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return expr, nil, err
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, t := range tokens {
+		if t.kind != tokenWord || !strings.EqualFold(t.key, "name") || (t.op != ":" && t.op != "=") {
+			continue
+		}
+		if ok, _ := filepath.Match(t.value, oldName); !ok {
+			continue
+		}
+		if t.value != oldName || strings.ContainsAny(newName, `*?[\`) {
+			if ok, _ := filepath.Match(t.value, newName); !ok {
+				stale = append(stale, t.text)
+			}
+			continue
+		}
+		sb.WriteString(expr[last:t.pos])
+		sb.WriteString(t.key + t.op + quoteValue(newName))
+		last = t.pos + len(t.text)
+	}
+	sb.WriteString(expr[last:])
+	return sb.String(), stale, nil
+}
+
+// quoteValue quotes value if the lexer would otherwise split it.
+func quoteValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n()\"") {
+		return value
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,39 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenameProject(t *testing.T) {
+	tests := []struct {
+		expr     string
+		old, new string
+		want     string
+		stale    []string
+	}{
+		{"name:api", "api", "web", "name:web", nil},
+		{"tag:go and (name=api or not name:api)", "api", "web", "tag:go and (name=web or not name:web)", nil},
+		{"NAME:api", "api", "web api", `NAME:"web api"`, nil},
+		{`name:"my api"`, "my api", "api", "name:api", nil},
+		{"name:apix or tag:api", "api", "web", "name:apix or tag:api", nil},
+		{"name:ap*", "api", "apps", "name:ap*", nil},
+		{"name:ap*", "api", "web", "name:ap*", []string{"name:ap*"}},
+		{"@saved name:api", "api", "web", "@saved name:web", nil},
+	}
+
+	for _, test := range tests {
+		got, stale, err := RenameProject(test.expr, test.old, test.new)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", test.expr, err)
+			continue
+		}
+		if got != test.want || !reflect.DeepEqual(stale, test.stale) {
+			t.Errorf("%q: got %q %q, want %q %q", test.expr, got, stale, test.want, test.stale)
+		}
+	}
+
+	if _, _, err := RenameProject(`name:"api`, "api", "web"); err == nil {
+		t.Errorf("expected an error for an unterminated quote")
+	}
+}
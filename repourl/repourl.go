@@ -0,0 +1,126 @@
+// Package repourl parses repository locations into host, owner and
+// repository name. https, ssh, git, file, scp-style (user@host:path) and
+// plain local paths are understood.
+package repourl
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LocalHost is used as Host for file:// URLs and local paths.
+const LocalHost = "local"
+
+// URL is a parsed repository location.
+type URL struct {
+	// Raw is the location as given by the user.
+	Raw string
+	// Host is the host name without user or port.
+	Host string
+	// Owner is the path between the host and the repository, e.g. the
+	// user, organisation or group. It may contain slashes for nested groups.
+	Owner string
+	// Repo is the repository name without a .git suffix.
+	Repo string
+	// Local is true for file:// URLs and local paths.
+	Local bool
+}
+
+var scpLike = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.+)$`)
+var windowsDrive = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+// Parse parses a repository location.
+func Parse(raw string) (URL, error) {
+	u := URL{Raw: raw}
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return u, fmt.Errorf("empty repository url")
+	}
+
+	var repoPath string
+	switch {
+	case strings.Contains(trimmed, "://"):
+		parsed, err := url.Parse(trimmed)
+		if err != nil {
+			return u, fmt.Errorf("invalid repository url '%s': %s", raw, err.Error())
+		}
+		switch parsed.Scheme {
+		case "file":
+			u.Host = LocalHost
+			u.Local = true
+		case "http", "https", "ssh", "git", "git+ssh", "ssh+git":
+			u.Host = parsed.Hostname()
+			if u.Host == "" {
+				return u, fmt.Errorf("repository url '%s' has no host", raw)
+			}
+		default:
+			return u, fmt.Errorf("unsupported scheme '%s' in repository url '%s'", parsed.Scheme, raw)
+		}
+		repoPath = parsed.Path
+	case windowsDrive.MatchString(trimmed):
+		u.Host = LocalHost
+		u.Local = true
+		repoPath = strings.Replace(trimmed, `\`, "/", -1)
+	case scpLike.MatchString(trimmed) && !strings.HasPrefix(trimmed, "/") && !strings.HasPrefix(trimmed, "."):
+		m := scpLike.FindStringSubmatch(trimmed)
+		u.Host = m[1]
+		repoPath = m[2]
+	default:
+		u.Host = LocalHost
+		u.Local = true
+		abs, err := filepath.Abs(trimmed)
+		if err != nil {
+			return u, err
+		}
+		repoPath = filepath.ToSlash(abs)
+	}
+
+	repoPath = strings.TrimRight(repoPath, "/")
+	repoPath = strings.TrimSuffix(repoPath, "/.git")
+	segments := strings.Split(strings.Trim(repoPath, "/"), "/")
+
+	u.Repo = strings.TrimSuffix(segments[len(segments)-1], ".git")
+	if u.Repo == "" {
+		return u, fmt.Errorf("could not determine repository name from '%s'", raw)
+	}
+	// The name and owner end up in the path of the checkout, they must not
+	// lead out of the directory it is placed in.
+	for _, segment := range segments {
+		if segment == "." || segment == ".." || strings.Contains(segment, `\`) {
+			return u, fmt.Errorf("invalid path segment '%s' in repository url '%s'", segment, raw)
+		}
+	}
+	if u.Repo == "." || u.Repo == ".." {
+		return u, fmt.Errorf("invalid repository name '%s' in '%s'", u.Repo, raw)
+	}
+
+	if u.Local {
+		// For local repositories only the directory containing the
+		// repository is meaningful as owner.
+		if len(segments) > 1 {
+			u.Owner = segments[len(segments)-2]
+		}
+	} else {
+		u.Owner = path.Join(segments[:len(segments)-1]...)
+	}
+
+	return u, nil
+}
+
+// CloneURL returns the location to hand to the version control tool. Local
+// paths are turned into file:// URLs when asFileURL is set, git ignores
+// --depth for plain local paths.
+func (u URL) CloneURL(asFileURL bool) string {
+	if !u.Local || !asFileURL || strings.HasPrefix(u.Raw, "file://") {
+		return u.Raw
+	}
+	abs, err := filepath.Abs(u.Raw)
+	if err != nil {
+		return u.Raw
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
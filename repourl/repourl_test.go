@@ -0,0 +1,108 @@
+package repourl
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	abs, err := filepath.Abs("repos/tool.git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := filepath.Base(filepath.Dir(abs))
+
+	tests := []struct {
+		raw  string
+		want URL
+	}{
+		{"https://github.com/Tebro/prj", URL{Host: "github.com", Owner: "Tebro", Repo: "prj"}},
+		{"https://github.com/Tebro/prj.git", URL{Host: "github.com", Owner: "Tebro", Repo: "prj"}},
+		{"https://user@gitlab.com:8443/group/sub/project.git/", URL{Host: "gitlab.com", Owner: "group/sub", Repo: "project"}},
+		{"http://host/repo", URL{Host: "host", Repo: "repo"}},
+		{"ssh://git@github.com:22/Tebro/prj.git", URL{Host: "github.com", Owner: "Tebro", Repo: "prj"}},
+		{"git://host/owner/repo", URL{Host: "host", Owner: "owner", Repo: "repo"}},
+		{"git+ssh://host/owner/repo", URL{Host: "host", Owner: "owner", Repo: "repo"}},
+		{"git@github.com:Tebro/prj.git", URL{Host: "github.com", Owner: "Tebro", Repo: "prj"}},
+		{"github.com:Tebro/prj", URL{Host: "github.com", Owner: "Tebro", Repo: "prj"}},
+		{"  git@host:prj  ", URL{Host: "host", Repo: "prj"}},
+		{"file:///srv/git/team/tool.git", URL{Host: LocalHost, Owner: "team", Repo: "tool", Local: true}},
+		{"file:///srv/git/tool/.git", URL{Host: LocalHost, Owner: "git", Repo: "tool", Local: true}},
+		{"/srv/git/team/tool.git", URL{Host: LocalHost, Owner: "team", Repo: "tool", Local: true}},
+		{"/tool", URL{Host: LocalHost, Repo: "tool", Local: true}},
+		{"./repos/tool.git", URL{Host: LocalHost, Owner: "repos", Repo: "tool", Local: true}},
+		{"repos/tool.git", URL{Host: LocalHost, Owner: "repos", Repo: "tool", Local: true}},
+		{"../" + parent + "/repos/tool.git", URL{Host: LocalHost, Owner: "repos", Repo: "tool", Local: true}},
+		{`C:\src\team\tool.git`, URL{Host: LocalHost, Owner: "team", Repo: "tool", Local: true}},
+		{"D:/src/tool", URL{Host: LocalHost, Owner: "src", Repo: "tool", Local: true}},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.raw)
+		if err != nil {
+			t.Errorf("Parse(%q): %s", test.raw, err)
+			continue
+		}
+		test.want.Raw = test.raw
+		if got != test.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.raw, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"", "empty repository url"},
+		{"   ", "empty repository url"},
+		{"ftp://host/owner/repo", "unsupported scheme 'ftp'"},
+		{"https:///owner/repo", "has no host"},
+		{"https://host/", "could not determine repository name"},
+		{"https://host/owner/..", "invalid path segment '..'"},
+		{"https://host/owner/.", "invalid path segment '.'"},
+		{"https://host/../../repo", "invalid path segment '..'"},
+		{"https://host/owner/..git", "invalid repository name '.'"},
+		{"https://host/owner/a%5Cb", `invalid path segment 'a\b'`},
+		{"git@host:owner/..", "invalid path segment '..'"},
+		{"file:///srv/../..", "invalid path segment '..'"},
+		{"%zz://host/repo", "invalid repository url"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.raw)
+		if err == nil {
+			t.Errorf("Parse(%q): expected an error containing %q", test.raw, test.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parse(%q): got error %q, want it to contain %q", test.raw, err.Error(), test.want)
+		}
+	}
+}
+
+func TestCloneURL(t *testing.T) {
+	tests := []struct {
+		raw       string
+		asFileURL bool
+		want      string
+	}{
+		{"https://github.com/Tebro/prj", true, "https://github.com/Tebro/prj"},
+		{"git@github.com:Tebro/prj.git", true, "git@github.com:Tebro/prj.git"},
+		{"file:///srv/tool.git", true, "file:///srv/tool.git"},
+		{"/srv/tool.git", false, "/srv/tool.git"},
+		{"/srv/tool.git", true, "file:///srv/tool.git"},
+	}
+
+	for _, test := range tests {
+		u, err := Parse(test.raw)
+		if err != nil {
+			t.Fatalf("Parse(%q): %s", test.raw, err)
+		}
+		if got := u.CloneURL(test.asFileURL); got != test.want {
+			t.Errorf("CloneURL(%q, %v) = %q, want %q", test.raw, test.asFileURL, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/hooks"
+	"github.com/Tebro/prj/scan"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// uniqueProjectName derives a project name from the directory name of path.
+// On collisions the names of parent directories below root are prepended
+// one by one, and as a last resort a number is appended.
+func uniqueProjectName(root string, path string, taken map[string]bool) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+
+	for i := len(segments) - 1; i >= 0; i-- {
+		name := strings.Join(segments[i:], "-")
+		if !taken[name] {
+			return name
+		}
+	}
+
+	base := strings.Join(segments, "-")
+	for i := 2; ; i++ {
+		name := fmt.Sprintf("%s-%d", base, i)
+		if !taken[name] {
+			return name
+		}
+	}
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+func confirm(c *cli.Context, question string) bool {
+	return confirmTo(c.App.Writer, question)
+}
+
+// confirmTo asks question on out and reads the answer from stdin.
+func confirmTo(out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func scanProjects(c *cli.Context) error {
+	roots := []string(c.Args())
+	if len(roots) == 0 {
+		roots = []string{getBaseDir(c)}
+	}
+
+	opts := scan.Options{
+		MaxDepth: c.Int("depth"),
+		Ignore:   append(append([]string{}, scan.DefaultIgnore...), c.StringSlice("ignore")...),
+		Workers:  c.Int("jobs"),
+	}
+	results, errs := scan.Scan(roots, opts)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "skipped: %s\n", err.Error())
+	}
+
+	registered := make(map[string]bool)
+	taken := make(map[string]bool)
+	for _, p := range db.GetProjects() {
+		registered[absPath(p.Path)] = true
+		taken[p.Name] = true
+	}
+
+	type candidate struct {
+		name   string
+		result scan.Result
+	}
+	var candidates []candidate
+	for _, r := range results {
+		if registered[r.Path] {
+			continue
+		}
+		name := uniqueProjectName(r.Root, r.Path, taken)
+		taken[name] = true
+		candidates = append(candidates, candidate{name: name, result: r})
+	}
+
+	if len(candidates) == 0 {
+		log(c, "No new projects found")
+		return nil
+	}
+
+	preview := ""
+	for _, cand := range candidates {
+		preview = fmt.Sprintf("%s%s: %s (%s)\n", preview, cand.name, cand.result.Path, strings.Join(cand.result.Markers, ", "))
+	}
+	log(c, `Found %d new projects
+--------
+%s`, len(candidates), preview)
+
+	if c.Bool("dry-run") {
+		return nil
+	}
+	if !c.Bool("yes") {
+		if !stdinIsTerminal() {
+			log(c, "Run again with --yes to register them")
+			return nil
+		}
+		if !confirm(c, fmt.Sprintf("Register %d projects?", len(candidates))) {
+			return nil
+		}
+	}
+
+	var added []string
+	for _, cand := range candidates {
+		path := cand.result.Path
+		hp := hookProject(c, cand.name, path, projectCategories(c, path))
+		if err := runHook(c, c.App.Writer, hooks.Pre(hooks.Add), hp); err != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s, aborted by %s hook: %s\n", cand.name, hooks.Pre(hooks.Add), err.Error())
+			continue
+		}
+		if err := db.AddProject(cand.name, path, hp.Categories); err != nil {
+			fmt.Fprintf(os.Stderr, "could not add %s: %s\n", cand.name, err.Error())
+			continue
+		}
+		runPostHook(c, hooks.Add, hp)
+		added = append(added, cand.name)
+	}
+	detectRegistered(added...)
+
+	log(c, "Registered %d projects", len(added))
+	return nil
+}
@@ -0,0 +1,214 @@
+// Package scan discovers project roots in directory trees.
+package scan
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Markers are the files and directories that identify a project root.
+var Markers = []string{
+	".git", ".hg", ".jj", ".fslckout", "_FOSSIL_",
+	"go.mod", "package.json", "Cargo.toml", "pyproject.toml",
+}
+
+// DefaultIgnore are directory name patterns that are never descended into.
+var DefaultIgnore = []string{"node_modules", "vendor", ".cache", ".Trash*"}
+
+// Options controls a scan.
+type Options struct {
+	// MaxDepth limits how many directories below a root are visited, 0
+	// means no limit.
+	MaxDepth int
+	// Ignore holds glob patterns matched against directory names.
+	Ignore []string
+	// Workers limits the number of directories read concurrently.
+	Workers int
+}
+
+// Result is a discovered project root.
+type Result struct {
+	// Root is the scan root the project was found under.
+	Root string
+	Path string
+	// Markers lists the marker files found in Path.
+	Markers []string
+}
+
+// job is a directory waiting to be visited.
+type job struct {
+	root  string
+	dir   string
+	depth int
+}
+
+// walker visits directory trees with a fixed number of workers that take
+// the directories from a shared queue.
+type walker struct {
+	opts    Options
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	queue   []job
+	pending int
+}
+
+// walk reads every directory below roots, calling visit with its entries or
+// the error reading it. Subdirectories are only visited if visit returns
+// true. visit is called concurrently.
+func walk(roots []string, opts Options, visit func(root string, dir string, entries []os.FileInfo, err error) bool) {
+	if opts.Workers <= 0 {
+		opts.Workers = 8
+	}
+	w := &walker{opts: opts}
+	w.cond = sync.NewCond(&w.mutex)
+	for _, root := range roots {
+		w.queue = append(w.queue, job{root: root, dir: root})
+	}
+	w.pending = len(w.queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				j, ok := w.next()
+				if !ok {
+					return
+				}
+				entries, err := ioutil.ReadDir(j.dir)
+				var subdirs []job
+				if visit(j.root, j.dir, entries, err) && (opts.MaxDepth <= 0 || j.depth < opts.MaxDepth) {
+					for _, e := range entries {
+						// Symlinked directories are not followed, they are
+						// not directories according to ReadDir.
+						if e.IsDir() && !w.ignored(e.Name()) {
+							subdirs = append(subdirs, job{root: j.root, dir: filepath.Join(j.dir, e.Name()), depth: j.depth + 1})
+						}
+					}
+				}
+				w.done(subdirs)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// next takes a directory from the queue, waiting while other workers may
+// still add some. It returns false when everything was visited.
+func (w *walker) next() (job, bool) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for len(w.queue) == 0 && w.pending > 0 {
+		w.cond.Wait()
+	}
+	if len(w.queue) == 0 {
+		return job{}, false
+	}
+	j := w.queue[len(w.queue)-1]
+	w.queue = w.queue[:len(w.queue)-1]
+	return j, true
+}
+
+// done queues the subdirectories of a visited directory.
+func (w *walker) done(subdirs []job) {
+	w.mutex.Lock()
+	w.queue = append(w.queue, subdirs...)
+	w.pending += len(subdirs) - 1
+	w.mutex.Unlock()
+	w.cond.Broadcast()
+}
+
+func (w *walker) ignored(name string) bool {
+	for _, pattern := range w.opts.Ignore {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan walks roots concurrently and returns the project roots below them,
+// sorted by path. Directories below a project root are not visited.
+// Unreadable directories are skipped and reported as errors next to the
+// results.
+func Scan(roots []string, opts Options) ([]Result, []error) {
+	var mutex sync.Mutex
+	var results []Result
+	var errors []error
+
+	var abs []string
+	for _, root := range roots {
+		dir, err := filepath.Abs(root)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		abs = append(abs, dir)
+	}
+
+	walk(abs, opts, func(root string, dir string, entries []os.FileInfo, err error) bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if err != nil {
+			errors = append(errors, err)
+			return false
+		}
+		if markers := findMarkers(entries); len(markers) > 0 {
+			results = append(results, Result{Root: root, Path: dir, Markers: markers})
+			return false
+		}
+		return true
+	})
+
+	sort.Slice(results, func(a int, b int) bool {
+		return results[a].Path < results[b].Path
+	})
+	return results, errors
+}
+
+func findMarkers(entries []os.FileInfo) []string {
+	var found []string
+	for _, e := range entries {
+		for _, m := range Markers {
+			if e.Name() == m {
+				found = append(found, m)
+			}
+		}
+	}
+	return found
+}
+
+// Find walks roots concurrently and returns all directories for which match
+// returns true, sorted by path. Directories below a match and version control
+// metadata directories are not visited.
+func Find(roots []string, opts Options, match func(dir string) bool) []string {
+	opts.Ignore = append(append([]string{}, opts.Ignore...), ".git", ".hg", ".jj")
+
+	var abs []string
+	for _, root := range roots {
+		dir, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		abs = append(abs, dir)
+	}
+
+	var mutex sync.Mutex
+	var found []string
+	walk(abs, opts, func(root string, dir string, entries []os.FileInfo, err error) bool {
+		if !match(dir) {
+			return err == nil
+		}
+		mutex.Lock()
+		found = append(found, dir)
+		mutex.Unlock()
+		return false
+	})
+
+	sort.Strings(found)
+	return found
+}
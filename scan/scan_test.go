@@ -0,0 +1,91 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func mkdir(t *testing.T, path string) {
+	t.Helper()
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	mkdir(t, filepath.Join(root, "a", ".git"))
+	mkdir(t, filepath.Join(root, "a", "nested", ".git"))
+	mkdir(t, filepath.Join(root, "work", "b", ".hg"))
+	mkdir(t, filepath.Join(root, "work", "node_modules", "c", ".git"))
+	mkdir(t, filepath.Join(root, "deep", "x", "y", "d", ".git"))
+
+	tests := []struct {
+		opts Options
+		want []string
+	}{
+		{Options{Ignore: DefaultIgnore}, []string{"a", "deep/x/y/d", "work/b"}},
+		{Options{Ignore: DefaultIgnore, MaxDepth: 2}, []string{"a", "work/b"}},
+		{Options{Workers: 1}, []string{"a", "deep/x/y/d", "work/b", "work/node_modules/c"}},
+	}
+
+	for _, test := range tests {
+		results, errs := Scan([]string{root}, test.opts)
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+		var got []string
+		for _, r := range results {
+			rel, _ := filepath.Rel(root, r.Path)
+			got = append(got, filepath.ToSlash(rel))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%+v: got %v, want %v", test.opts, got, test.want)
+		}
+	}
+}
+
+func TestScanManyDirectories(t *testing.T) {
+	root := t.TempDir()
+	var want []string
+	for i := 0; i < 50; i++ {
+		dir := filepath.Join(root, strconv.Itoa(i))
+		for j := 0; j < 10; j++ {
+			mkdir(t, filepath.Join(dir, strconv.Itoa(j)))
+		}
+		mkdir(t, filepath.Join(dir, "5", "p", ".git"))
+		want = append(want, filepath.Join(dir, "5", "p"))
+	}
+
+	results, errs := Scan([]string{root}, Options{Workers: 3})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	sort.Strings(want)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Path)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	mkdir(t, filepath.Join(root, "a", "want", "want"))
+	mkdir(t, filepath.Join(root, "b", ".git", "want"))
+	mkdir(t, filepath.Join(root, "b", "c", "want"))
+
+	found := Find([]string{root}, Options{Ignore: DefaultIgnore}, func(dir string) bool {
+		return filepath.Base(dir) == "want"
+	})
+	want := []string{filepath.Join(root, "a", "want"), filepath.Join(root, "b", "c", "want")}
+	if !reflect.DeepEqual(found, want) {
+		t.Errorf("got %v, want %v", found, want)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/query"
+	"github.com/Tebro/prj/selector"
+	"gopkg.in/urfave/cli.v1"
+	"strings"
+	"time"
+)
+
+// selectorWorkers limits how many projects are inspected concurrently when a
+// filter needs the file system or version control.
+const selectorWorkers = 8
+
+// selectorFlags are the flags of every command that works on a selection of
+// projects.
+func selectorFlags() []cli.Flag {
+	return append(filterFlags(),
+		cli.StringFlag{
+			Name:  "sort",
+			Value: "path",
+			Usage: "Sort by " + strings.Join(selector.SortKeys, ", "),
+		},
+		cli.BoolFlag{
+			Name:  "reverse",
+			Usage: "Reverse the sort order",
+		},
+		cli.IntFlag{
+			Name:  "limit",
+			Usage: "Show at most this many projects",
+		},
+	)
+}
+
+// filterFlags are the selector flags that choose projects, for commands with
+// their own ordering.
+func filterFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.BoolFlag{
+			Name:  "all, a",
+			Usage: "Include archived projects",
+		},
+		cli.StringSliceFlag{
+			Name:  "categories, c",
+			Usage: "Only projects in this category, e.g. -c work/acme or -c work -c acme",
+		},
+		cli.StringFlag{
+			Name:  "name",
+			Usage: "Only projects whose name matches this glob pattern",
+		},
+		cli.StringFlag{
+			Name:  "name-regex",
+			Usage: "Only projects whose name matches this regular expression",
+		},
+		cli.StringFlag{
+			Name:  "path",
+			Usage: "Only projects in this directory or below it",
+		},
+		cli.StringSliceFlag{
+			Name:  "tag",
+			Usage: "Only projects with this tag, can be given several times",
+		},
+		cli.StringSliceFlag{
+			Name:  "lang",
+			Usage: "Only projects written in this language, can be given several times",
+		},
+		cli.StringSliceFlag{
+			Name:  "build",
+			Usage: "Only projects using this build system, can be given several times",
+		},
+		cli.StringFlag{
+			Name:  "vcs",
+			Usage: "Only projects using this version control system, none for projects without",
+		},
+		cli.BoolFlag{
+			Name:  "missing",
+			Usage: "Only projects whose directory does not exist",
+		},
+		cli.BoolFlag{
+			Name:  "present",
+			Usage: "Only projects whose directory exists",
+		},
+		cli.BoolFlag{
+			Name:  "dirty",
+			Usage: "Only projects with uncommitted changes",
+		},
+		cli.BoolFlag{
+			Name:  "clean",
+			Usage: "Only projects without uncommitted changes",
+		},
+		cli.StringFlag{
+			Name:  "modified-before",
+			Usage: "Only projects not modified for this long, e.g. 90d, 2w or 12h",
+		},
+	}
+}
+
+// queryArgs returns the arguments that select projects. 'prj exec' takes
+// the command to run after them.
+func queryArgs(c *cli.Context) []string {
+	if c.Command.Name == "exec" {
+		args, _ := splitExecArgs(c)
+		return args
+	}
+	return c.Args()
+}
+
+// selectorFilter builds the filter described by the query arguments and the
+// selector flags.
+func selectorFilter(c *cli.Context) (selector.Filter, error) {
+	var filters []selector.Filter
+	if args := queryArgs(c); len(args) > 0 {
+		f, err := query.Parse(strings.Join(args, " "), db.GetQuery)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if category := splitCategories(c.StringSlice("categories")); len(category) > 0 {
+		filters = append(filters, selector.Category(category))
+	}
+	if pattern := c.String("name"); pattern != "" {
+		f, err := selector.Name(pattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if expr := c.String("name-regex"); expr != "" {
+		f, err := selector.NameRegexp(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if path := c.String("path"); path != "" {
+		filters = append(filters, selector.PathPrefix(absPath(path)))
+	}
+	for _, tag := range c.StringSlice("tag") {
+		filters = append(filters, selector.Tag(tag))
+	}
+	for _, language := range c.StringSlice("lang") {
+		filters = append(filters, selector.Language(language))
+	}
+	for _, build := range c.StringSlice("build") {
+		filters = append(filters, selector.BuildSystem(build))
+	}
+	if name := c.String("vcs"); name != "" {
+		filters = append(filters, selector.VCS(name))
+	}
+	if c.Bool("missing") {
+		filters = append(filters, selector.Missing)
+	}
+	if c.Bool("present") {
+		filters = append(filters, selector.Not(selector.Missing))
+	}
+	if c.Bool("dirty") {
+		filters = append(filters, selector.Dirty)
+	}
+	if c.Bool("clean") {
+		filters = append(filters, selector.Not(selector.Dirty))
+	}
+	if age := c.String("modified-before"); age != "" {
+		d, err := selector.ParseAge(age)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, selector.ModifiedBefore(time.Now().Add(-d)))
+	}
+	return selector.All(filters...), nil
+}
+
+// selectProjects returns the projects chosen with the query arguments and the
+// selector flags, sorted and limited as requested.
+func selectProjects(c *cli.Context) ([]db.Project, error) {
+	return selectFrom(c, db.GetProjectList(c.Bool("all")))
+}
+
+// selectTargets returns the projects a command should work on: the project
+// named by the only argument, or the projects selected from candidates.
+func selectTargets(c *cli.Context, candidates []db.Project) ([]db.Project, error) {
+	if args := queryArgs(c); len(args) == 1 {
+		if p, err := db.GetProject(args[0]); err == nil {
+			return []db.Project{p}, nil
+		}
+	}
+	return selectFrom(c, candidates)
+}
+
+// filterTargets is selectTargets for commands with their own ordering.
+func filterTargets(c *cli.Context, candidates []db.Project) ([]*selector.Item, error) {
+	if args := queryArgs(c); len(args) == 1 {
+		if p, err := db.GetProject(args[0]); err == nil {
+			return []*selector.Item{selector.NewItem(p, getProjectCategories(c, p))}, nil
+		}
+	}
+	return filterProjects(c, candidates)
+}
+
+// needsDetection reports whether the selection filters on the languages or
+// build systems of projects. Only then stale classifications are refreshed,
+// otherwise the cached ones are good enough.
+func needsDetection(c *cli.Context) bool {
+	if len(c.StringSlice("lang")) > 0 || len(c.StringSlice("build")) > 0 {
+		return true
+	}
+	return query.UsesKeys(strings.Join(queryArgs(c), " "), db.GetQuery, "lang", "build")
+}
+
+// filterProjects returns the items of the projects matching the query
+// arguments and the selector flags, in their original order.
+func filterProjects(c *cli.Context, projects []db.Project) ([]*selector.Item, error) {
+	filter, err := selectorFilter(c)
+	if err != nil {
+		return nil, exitErrorWrapper("%s", err.Error())
+	}
+	if needsDetection(c) {
+		projects = detectProjects(projects, false)
+	}
+
+	var items []*selector.Item
+	for _, p := range projects {
+		items = append(items, selector.NewItem(p, getProjectCategories(c, p)))
+	}
+	return selector.Select(items, filter, selectorWorkers), nil
+}
+
+func selectFrom(c *cli.Context, projects []db.Project) ([]db.Project, error) {
+	items, err := filterProjects(c, projects)
+	if err != nil {
+		return nil, err
+	}
+
+	key := c.String("sort")
+	if key == "" {
+		key = "path"
+	}
+	if err := selector.Sort(items, key, c.Bool("reverse"), selectorWorkers); err != nil {
+		return nil, exitErrorWrapper("%s", err.Error())
+	}
+	if limit := c.Int("limit"); limit > 0 && len(items) > limit {
+		items = items[:limit]
+	}
+
+	selected := []db.Project{}
+	for _, item := range items {
+		selected = append(selected, item.Project)
+	}
+	return selected, nil
+}
@@ -0,0 +1,211 @@
+package selector
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter decides whether an item is selected.
+type Filter func(item *Item) bool
+
+// All selects items selected by every filter, it selects everything if there
+// are no filters.
+func All(filters ...Filter) Filter {
+	return func(item *Item) bool {
+		for _, f := range filters {
+			if !f(item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Any selects items selected by at least one of the filters.
+func Any(filters ...Filter) Filter {
+	return func(item *Item) bool {
+		for _, f := range filters {
+			if f(item) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a filter.
+func Not(f Filter) Filter {
+	return func(item *Item) bool {
+		return !f(item)
+	}
+}
+
+// Name selects projects whose name matches the glob pattern.
+func Name(pattern string) (Filter, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid name pattern '%s': %s", pattern, err.Error())
+	}
+	return func(item *Item) bool {
+		ok, _ := filepath.Match(pattern, item.Name)
+		return ok
+	}, nil
+}
+
+// NameRegexp selects projects whose name matches the regular expression.
+func NameRegexp(expr string) (Filter, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name expression '%s': %s", expr, err.Error())
+	}
+	return func(item *Item) bool {
+		return re.MatchString(item.Name)
+	}, nil
+}
+
+// PathPrefix selects projects in dir or below it.
+func PathPrefix(dir string) Filter {
+	dir = filepath.Clean(dir)
+	return func(item *Item) bool {
+		path := filepath.Clean(item.Path)
+		return path == dir || strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+	}
+}
+
+// Category selects projects in the category or one of its subcategories,
+// given as its path segments.
+func Category(category []string) Filter {
+	return func(item *Item) bool {
+		if len(category) > len(item.Categories) {
+			return false
+		}
+		for i := range category {
+			if item.Categories[i] != category[i] {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Tag selects projects with the tag.
+func Tag(tag string) Filter {
+	return func(item *Item) bool {
+		for _, t := range item.Tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Language selects projects in which the language was detected, ignoring
+// case.
+func Language(language string) Filter {
+	return func(item *Item) bool {
+		return item.Detection != nil && containsFold(item.Detection.Languages, language)
+	}
+}
+
+// BuildSystem selects projects in which the build system was detected,
+// ignoring case.
+func BuildSystem(build string) Filter {
+	return func(item *Item) bool {
+		return item.Detection != nil && containsFold(item.Detection.BuildSystems, build)
+	}
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// VCS selects projects using the version control system, none selects
+// projects without one.
+func VCS(name string) Filter {
+	if name == "none" {
+		name = ""
+	}
+	return func(item *Item) bool {
+		return item.VCS() == name
+	}
+}
+
+// Missing selects projects whose directory does not exist.
+func Missing(item *Item) bool {
+	return !item.Exists()
+}
+
+// Dirty selects projects with uncommitted changes.
+func Dirty(item *Item) bool {
+	return item.Dirty()
+}
+
+// Archived selects archived projects.
+func Archived(item *Item) bool {
+	return item.Project.Archived
+}
+
+// ModifiedBefore selects projects that have not been modified since t.
+// Missing projects are not selected.
+func ModifiedBefore(t time.Time) Filter {
+	return func(item *Item) bool {
+		return item.Exists() && item.Modified().Before(t)
+	}
+}
+
+// ModifiedAfter selects projects that have been modified since t.
+func ModifiedAfter(t time.Time) Filter {
+	return func(item *Item) bool {
+		return item.Exists() && item.Modified().After(t)
+	}
+}
+
+var ageUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+	"y": 365 * 24 * time.Hour,
+}
+
+var agePattern = regexp.MustCompile(`^([0-9]+)([mhdwy])$`)
+
+// ParseAge parses ages like 90d, 2w or 12h. The units are m (minutes), h,
+// d, w and y (365 days).
+func ParseAge(s string) (time.Duration, error) {
+	m := agePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid age '%s', expected a number followed by m, h, d, w or y, e.g. 90d", s)
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid age '%s': %s", s, err.Error())
+	}
+	return time.Duration(n) * ageUnits[m[2]], nil
+}
+
+// Select returns the items selected by filter in their original order,
+// evaluating the filter for up to workers items concurrently.
+func Select(items []*Item, filter Filter, workers int) []*Item {
+	selected := make([]bool, len(items))
+	each(items, workers, func(i int, item *Item) {
+		selected[i] = filter(item)
+	})
+
+	var out []*Item
+	for i, item := range items {
+		if selected[i] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
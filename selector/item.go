@@ -0,0 +1,160 @@
+// Package selector filters and sorts projects. Filters are plain functions
+// that can be combined, so the same selection logic is shared by ls and the
+// commands that work on several projects at once.
+package selector
+
+import (
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/fsutil"
+	"github.com/Tebro/prj/vcs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Item is a project being selected. Facts that need the file system or a
+// version control command are looked up on first use and cached, so filters
+// only pay for what they ask.
+type Item struct {
+	db.Project
+	// Categories are the categories of the project, resolved by the caller
+	// for projects that don't have them stored.
+	Categories []string
+
+	statOnce sync.Once
+	exists   bool
+	vcs      vcs.VCS
+
+	dirtyOnce sync.Once
+	dirty     bool
+
+	modifiedOnce sync.Once
+	modified     time.Time
+
+	sizeOnce sync.Once
+	size     int64
+}
+
+// NewItem returns an item for p with the given categories.
+func NewItem(p db.Project, categories []string) *Item {
+	return &Item{Project: p, Categories: categories}
+}
+
+func (i *Item) stat() {
+	i.statOnce.Do(func() {
+		stat, err := os.Stat(i.Path)
+		i.exists = err == nil && stat.IsDir()
+		if i.exists {
+			i.vcs, _ = vcs.Detect(i.Path)
+		}
+	})
+}
+
+// Exists reports whether the project directory exists.
+func (i *Item) Exists() bool {
+	i.stat()
+	return i.exists
+}
+
+// VCS returns the name of the version control system of the project, or an
+// empty string if it has none.
+func (i *Item) VCS() string {
+	i.stat()
+	if i.vcs == nil {
+		return ""
+	}
+	return i.vcs.Name()
+}
+
+// Dirty reports whether the working copy has uncommitted changes. Projects
+// without version control are never dirty.
+func (i *Item) Dirty() bool {
+	i.dirtyOnce.Do(func() {
+		i.stat()
+		if i.vcs != nil {
+			i.dirty, _ = i.vcs.IsDirty(i.Path)
+		}
+	})
+	return i.dirty
+}
+
+// Modified returns when the project was last worked on: the newest of its
+// last commit and the modification times of the project directory and the
+// entries directly inside it.
+func (i *Item) Modified() time.Time {
+	i.modifiedOnce.Do(func() {
+		i.stat()
+		if !i.exists {
+			return
+		}
+		if stat, err := os.Stat(i.Path); err == nil {
+			i.modified = stat.ModTime()
+		}
+		if entries, err := ioutil.ReadDir(i.Path); err == nil {
+			for _, e := range entries {
+				if !fsutil.IsVCSMetadata(e.Name()) && e.ModTime().After(i.modified) {
+					i.modified = e.ModTime()
+				}
+			}
+		}
+		if i.vcs != nil {
+			if commit, err := i.vcs.LastCommit(i.Path); err == nil && commit.Date.After(i.modified) {
+				i.modified = commit.Date
+			}
+		}
+	})
+	return i.modified
+}
+
+// Size returns the total size in bytes of the files in the project.
+func (i *Item) Size() int64 {
+	i.sizeOnce.Do(func() {
+		filepath.Walk(i.Path, func(path string, info os.FileInfo, err error) error {
+			if err == nil && info.Mode().IsRegular() {
+				i.size += info.Size()
+			}
+			return nil
+		})
+	})
+	return i.size
+}
+
+// Frecency combines how often and how recently the project was visited with
+// goto, recent visits weigh more.
+func (i *Item) Frecency(now time.Time) float64 {
+	if i.AccessCount == 0 {
+		return 0
+	}
+	age := now.Sub(i.Accessed)
+	switch {
+	case age < time.Hour:
+		return float64(i.AccessCount) * 4
+	case age < 24*time.Hour:
+		return float64(i.AccessCount) * 2
+	case age < 7*24*time.Hour:
+		return float64(i.AccessCount) / 2
+	default:
+		return float64(i.AccessCount) / 4
+	}
+}
+
+// each calls fn for all items using up to workers goroutines.
+func each(items []*Item, workers int, fn func(i int, item *Item)) {
+	if workers <= 0 {
+		workers = 8
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, item *Item) {
+			defer wg.Done()
+			fn(i, item)
+			<-sem
+		}(i, item)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,44 @@
+package selector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortKeys lists the keys items can be sorted by.
+var SortKeys = []string{"name", "path", "created", "accessed", "size", "frecency"}
+
+// Sort orders items by key. Dates, sizes and frecency sort the newest,
+// largest and most used first, reverse flips the order.
+func Sort(items []*Item, key string, reverse bool, workers int) error {
+	now := time.Now()
+	var less func(a *Item, b *Item) bool
+	switch key {
+	case "name":
+		less = func(a *Item, b *Item) bool { return a.Name < b.Name }
+	case "path":
+		less = func(a *Item, b *Item) bool { return a.Path < b.Path }
+	case "created":
+		less = func(a *Item, b *Item) bool { return a.Created.After(b.Created) }
+	case "accessed":
+		less = func(a *Item, b *Item) bool { return a.Accessed.After(b.Accessed) }
+	case "size":
+		// Sizes take a walk of the whole tree, compute them concurrently.
+		each(items, workers, func(i int, item *Item) { item.Size() })
+		less = func(a *Item, b *Item) bool { return a.Size() > b.Size() }
+	case "frecency":
+		less = func(a *Item, b *Item) bool { return a.Frecency(now) > b.Frecency(now) }
+	default:
+		return fmt.Errorf("unknown sort key '%s', expected one of %s", key, strings.Join(SortKeys, ", "))
+	}
+
+	sort.SliceStable(items, func(a int, b int) bool {
+		if reverse {
+			return less(items[b], items[a])
+		}
+		return less(items[a], items[b])
+	})
+	return nil
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/vcs"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// statusRecord is the working copy state of a project as shown by 'prj
+// status'. Error is set if the state could not be determined.
+type statusRecord struct {
+	Name      string
+	Path      string
+	VCS       string
+	Branch    string
+	Detached  bool
+	Head      string
+	Upstream  string
+	Ahead     int
+	Behind    int
+	Staged    int
+	Unstaged  int
+	Untracked int
+	Conflicts int
+	Stashes   int
+	Dirty     bool
+	Error     string
+}
+
+// needsAttention reports whether the project has work that only exists in
+// this checkout: uncommitted changes, unpushed commits, stashes or a detached
+// HEAD. Projects whose state is unknown need attention too.
+func (r statusRecord) needsAttention() bool {
+	return r.Dirty || r.Ahead > 0 || r.Stashes > 0 || r.Detached || r.Error != ""
+}
+
+func projectStatus(p db.Project) (statusRecord, bool) {
+	r := statusRecord{Name: p.Name, Path: p.Path}
+	if isDir, _ := pathIsDir(p.Path); !isDir {
+		r.Error = "project directory is missing"
+		return r, true
+	}
+	v, ok := vcs.Detect(p.Path)
+	if !ok {
+		return r, false
+	}
+	r.VCS = v.Name()
+
+	s, err := v.Status(p.Path)
+	if err != nil {
+		r.Error = err.Error()
+		return r, true
+	}
+	r.Branch, r.Detached, r.Head, r.Upstream = s.Branch, s.Detached, s.Head, s.Upstream
+	r.Ahead, r.Behind, r.Stashes = s.Ahead, s.Behind, s.Stashes
+	r.Staged, r.Unstaged, r.Untracked, r.Conflicts = s.Staged, s.Unstaged, s.Untracked, s.Conflicts
+	r.Dirty = s.Dirty()
+	return r, true
+}
+
+// collectStatus inspects projects with up to jobs workers. Projects without
+// version control are left out, the order of projects is kept.
+func collectStatus(projects []db.Project, jobs int) []statusRecord {
+	if jobs < 1 {
+		jobs = 1
+	}
+	records := make([]statusRecord, len(projects))
+	found := make([]bool, len(projects))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				records[i], found[i] = projectStatus(projects[i])
+			}
+		}()
+	}
+	for i := range projects {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	var out []statusRecord
+	for i, r := range records {
+		if found[i] {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// changesSummary abbreviates the changes like shell prompts do: +staged
+// ~unstaged ?untracked !conflicts.
+func changesSummary(r statusRecord) string {
+	var parts []string
+	for _, part := range []struct {
+		sign  string
+		count int
+	}{{"+", r.Staged}, {"~", r.Unstaged}, {"?", r.Untracked}, {"!", r.Conflicts}} {
+		if part.count > 0 {
+			parts = append(parts, fmt.Sprintf("%s%d", part.sign, part.count))
+		}
+	}
+	if len(parts) == 0 {
+		return "clean"
+	}
+	return strings.Join(parts, " ")
+}
+
+func upstreamSummary(r statusRecord) string {
+	if r.Upstream == "" {
+		return "-"
+	}
+	summary := r.Upstream
+	if r.Ahead > 0 {
+		summary += fmt.Sprintf(" ahead %d", r.Ahead)
+	}
+	if r.Behind > 0 {
+		summary += fmt.Sprintf(" behind %d", r.Behind)
+	}
+	return summary
+}
+
+func branchSummary(r statusRecord) string {
+	if r.Detached {
+		return fmt.Sprintf("(detached %s)", r.Head)
+	}
+	return r.Branch
+}
+
+func showStatus(c *cli.Context) error {
+	projects, err := selectTargets(c, db.GetProjectList(c.Bool("all")))
+	if err != nil {
+		return err
+	}
+
+	var records []statusRecord
+	for _, r := range collectStatus(projects, c.Int("jobs")) {
+		if !c.Bool("only-dirty") || r.Dirty || r.Error != "" {
+			records = append(records, r)
+		}
+	}
+	attention := 0
+	for _, r := range records {
+		if r.needsAttention() {
+			attention++
+		}
+	}
+
+	if c.String("format") != "" {
+		if records == nil {
+			records = []statusRecord{}
+		}
+		if err := writeFormatted(c, records); err != nil {
+			return err
+		}
+	} else if len(records) > 0 {
+		w := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "PROJECT\tBRANCH\tCHANGES\tUPSTREAM\tSTASHES")
+		for _, r := range records {
+			if r.Error != "" {
+				fmt.Fprintf(w, "%s\t-\terror\t-\t-\n", r.Name)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", r.Name, branchSummary(r), changesSummary(r), upstreamSummary(r), r.Stashes)
+		}
+		w.Flush()
+		for _, r := range records {
+			if r.Error != "" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", r.Name, r.Error)
+			}
+		}
+	} else if c.Bool("only-dirty") {
+		log(c, "No project has uncommitted changes")
+	}
+
+	if attention > 0 {
+		// Detections refreshed while selecting are kept despite the exit.
+		db.PrepareForShutdown()
+		return exitErrorWrapper("%d projects need attention", attention)
+	}
+	return nil
+}
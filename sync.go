@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/vcs"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// Results of syncing a project.
+const (
+	syncUpdated  = "updated"
+	syncUpToDate = "up to date"
+	syncSkipped  = "skipped"
+	syncFailed   = "failed"
+)
+
+// syncRecord is what 'prj sync' did to a project.
+type syncRecord struct {
+	Name    string
+	Path    string
+	Branch  string
+	Result  string
+	Details string
+}
+
+// syncProject fetches the remotes of the git project at path and fast-forwards
+// the checked out branch if it is clean and only behind its upstream.
+func syncProject(ctx context.Context, p db.Project) syncRecord {
+	r := syncRecord{Name: p.Name, Path: p.Path}
+	fail := func(err error) syncRecord {
+		r.Result, r.Details = syncFailed, err.Error()
+		return r
+	}
+	skip := func(reason string) syncRecord {
+		r.Result, r.Details = syncSkipped, reason
+		return r
+	}
+
+	git := vcs.Git{}
+	if err := git.Fetch(ctx, p.Path); err != nil {
+		return fail(err)
+	}
+	s, err := git.StatusContext(ctx, p.Path)
+	if err != nil {
+		return fail(err)
+	}
+	r.Branch = s.Branch
+
+	switch {
+	case s.Detached:
+		return skip(fmt.Sprintf("detached HEAD at %s", s.Head))
+	case s.Upstream == "":
+		return skip("no upstream branch")
+	case s.Behind == 0:
+		r.Result = syncUpToDate
+		if s.Ahead > 0 {
+			r.Details = fmt.Sprintf("%d commits ahead of %s", s.Ahead, s.Upstream)
+		}
+		return r
+	case s.Ahead > 0:
+		return skip(fmt.Sprintf("diverged from %s, %d commits ahead and %d behind", s.Upstream, s.Ahead, s.Behind))
+	case s.Dirty():
+		return skip(fmt.Sprintf("uncommitted changes, %d commits behind %s", s.Behind, s.Upstream))
+	}
+
+	if err := git.FastForward(ctx, p.Path); err != nil {
+		return fail(err)
+	}
+	r.Result, r.Details = syncUpdated, fmt.Sprintf("fast-forwarded %d commits from %s", s.Behind, s.Upstream)
+	return r
+}
+
+func syncProjects(c *cli.Context) error {
+	projects, err := selectTargets(c, db.GetProjectList(c.Bool("all")))
+	if err != nil {
+		return err
+	}
+
+	// Only git checkouts are synced, jj keeps its own view of the
+	// repository even when it shares it with git.
+	var repos []db.Project
+	for _, p := range projects {
+		if v, ok := vcs.Detect(p.Path); ok && v.Name() == "git" {
+			repos = append(repos, p)
+		}
+	}
+	if len(repos) == 0 {
+		return exitErrorWrapper("no git projects selected")
+	}
+
+	jobs := c.Int("jobs")
+	if jobs < 1 {
+		jobs = 1
+	}
+	timeout := c.Duration("timeout")
+	// The commands run in their own process groups, an interrupt has to stop
+	// them through the context.
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	records := make([]syncRecord, len(repos))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				var ctx context.Context
+				var cancel context.CancelFunc
+				if timeout > 0 {
+					ctx, cancel = context.WithTimeout(interrupted, timeout)
+				} else {
+					ctx, cancel = context.WithCancel(interrupted)
+				}
+				records[i] = syncProject(ctx, repos[i])
+				cancel()
+			}
+		}()
+	}
+	for i := range repos {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	counts := make(map[string]int)
+	for _, r := range records {
+		counts[r.Result]++
+	}
+
+	if c.String("format") != "" {
+		if err := writeFormatted(c, records); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(c.App.Writer, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "PROJECT\tBRANCH\tRESULT\tDETAILS")
+		for _, r := range records {
+			// Errors of git span several lines, the first says what went wrong.
+			details := strings.SplitN(r.Details, "\n", 2)[0]
+			branch := r.Branch
+			if branch == "" {
+				branch = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, branch, r.Result, details)
+		}
+		w.Flush()
+
+		var summary []string
+		for _, result := range []string{syncUpdated, syncUpToDate, syncSkipped, syncFailed} {
+			if counts[result] > 0 {
+				summary = append(summary, fmt.Sprintf("%d %s", counts[result], result))
+			}
+		}
+		log(c, "%s", strings.Join(summary, ", "))
+	}
+
+	if counts[syncFailed] > 0 {
+		// Detections refreshed while selecting are kept despite the exit.
+		db.PrepareForShutdown()
+		return exitErrorWrapper("%d projects could not be synced", counts[syncFailed])
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"github.com/Tebro/prj/db"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// git runs git in dir and fails the test if it doesn't succeed.
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %s\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes content to name in dir and commits it.
+func commit(t *testing.T, dir string, name string, content string) {
+	t.Helper()
+	if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "add", name)
+	git(t, dir, "commit", "--quiet", "-m", "change "+name)
+}
+
+// syncFixture returns a clone of a bare repository and a second clone of it
+// that stands in for somebody else pushing to it.
+func syncFixture(t *testing.T) (local string, other string) {
+	t.Helper()
+	for key, value := range map[string]string{
+		"GIT_CONFIG_NOSYSTEM": "1",
+		"GIT_CONFIG_GLOBAL":   filepath.Join(t.TempDir(), "gitconfig"),
+		"GIT_AUTHOR_NAME":     "Test",
+		"GIT_AUTHOR_EMAIL":    "test@example.com",
+		"GIT_COMMITTER_NAME":  "Test",
+		"GIT_COMMITTER_EMAIL": "test@example.com",
+	} {
+		t.Setenv(key, value)
+	}
+
+	dir := t.TempDir()
+	remote := filepath.Join(dir, "remote.git")
+	git(t, dir, "init", "--quiet", "--bare", remote)
+	other = filepath.Join(dir, "other")
+	git(t, dir, "clone", "--quiet", remote, other)
+	commit(t, other, "README", "first")
+	git(t, other, "push", "--quiet", "origin", "HEAD")
+	local = filepath.Join(dir, "local")
+	git(t, dir, "clone", "--quiet", remote, local)
+	return local, other
+}
+
+func TestSyncProject(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, local string, other string)
+		result  string
+		details string
+		moved   bool
+	}{
+		{
+			name:    "up to date",
+			prepare: func(t *testing.T, local string, other string) {},
+			result:  syncUpToDate,
+		},
+		{
+			name: "fast-forward",
+			prepare: func(t *testing.T, local string, other string) {
+				commit(t, other, "README", "second")
+				git(t, other, "push", "--quiet")
+			},
+			result:  syncUpdated,
+			details: "fast-forwarded 1 commits",
+			moved:   true,
+		},
+		{
+			name: "dirty",
+			prepare: func(t *testing.T, local string, other string) {
+				commit(t, other, "README", "second")
+				git(t, other, "push", "--quiet")
+				if err := ioutil.WriteFile(filepath.Join(local, "README"), []byte("mine"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			result:  syncSkipped,
+			details: "uncommitted changes, 1 commits behind",
+		},
+		{
+			name: "diverged",
+			prepare: func(t *testing.T, local string, other string) {
+				commit(t, other, "README", "second")
+				git(t, other, "push", "--quiet")
+				commit(t, local, "LOCAL", "mine")
+			},
+			result:  syncSkipped,
+			details: "1 commits ahead and 1 behind",
+		},
+		{
+			name: "no upstream",
+			prepare: func(t *testing.T, local string, other string) {
+				git(t, local, "checkout", "--quiet", "-b", "topic")
+			},
+			result:  syncSkipped,
+			details: "no upstream branch",
+		},
+		{
+			name: "remote gone",
+			prepare: func(t *testing.T, local string, other string) {
+				git(t, local, "remote", "set-url", "origin", filepath.Join(t.TempDir(), "missing.git"))
+			},
+			result: syncFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			local, other := syncFixture(t)
+			test.prepare(t, local, other)
+			before := git(t, local, "rev-parse", "HEAD")
+
+			r := syncProject(context.Background(), db.Project{Name: "p", Path: local})
+			if r.Result != test.result || !strings.Contains(r.Details, test.details) {
+				t.Errorf("got %s %q, want %s %q", r.Result, r.Details, test.result, test.details)
+			}
+			after := git(t, local, "rev-parse", "HEAD")
+			if moved := before != after; moved != test.moved {
+				t.Errorf("HEAD moved: %v, want %v", moved, test.moved)
+			}
+			if test.moved && after != git(t, other, "rev-parse", "HEAD") {
+				t.Errorf("HEAD is at %s, not at the pushed commit", after)
+			}
+		})
+	}
+}
+
+func TestSyncProjectTimeout(t *testing.T) {
+	local, _ := syncFixture(t)
+	git(t, local, "remote", "set-url", "origin", "ssh://example.invalid/repo.git")
+	t.Setenv("GIT_SSH_COMMAND", "sh -c 'sleep 60' --")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	r := syncProject(ctx, db.Project{Name: "p", Path: local})
+	if r.Result != syncFailed || !strings.Contains(r.Details, "timed out") {
+		t.Errorf("got %s %q, want %s with a timeout", r.Result, r.Details, syncFailed)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("took %s to give up", elapsed)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/Tebro/prj/db"
+	"gopkg.in/urfave/cli.v1"
+	"sort"
+	"strings"
+)
+
+// mergeTags returns tags with add added and remove removed, sorted and
+// without duplicates.
+func mergeTags(tags []string, add []string, remove []string) []string {
+	set := make(map[string]bool)
+	for _, t := range append(tags, add...) {
+		set[t] = true
+	}
+	for _, t := range remove {
+		delete(set, t)
+	}
+
+	var merged []string
+	for t := range set {
+		merged = append(merged, t)
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+func tagProject(c *cli.Context) error {
+	if c.NArg() < 1 {
+		return exitErrorWrapper("invalid number of arguments, expected at least 1")
+	}
+
+	name := c.Args()[0]
+	p, err := db.GetProject(name)
+	if err != nil {
+		return exitErrorWrapper("could not tag project: %s", err.Error())
+	}
+
+	tags := c.Args()[1:]
+	if len(tags) == 0 {
+		log(c, "%s", strings.Join(p.Tags, " "))
+		return nil
+	}
+	for _, tag := range tags {
+		if tag == "" || strings.ContainsAny(tag, " \t,") {
+			return exitErrorWrapper("invalid tag '%s', tags can't be empty or contain spaces or commas", tag)
+		}
+	}
+
+	// Removed tags are remembered, so the AutoTag rules leave them off.
+	if c.Bool("delete") {
+		db.SetProjectTags(name, mergeTags(p.Tags, nil, tags))
+		db.SetProjectRemovedTags(name, mergeTags(p.RemovedTags, tags, nil))
+	} else {
+		db.SetProjectTags(name, mergeTags(p.Tags, tags, nil))
+		db.SetProjectRemovedTags(name, mergeTags(p.RemovedTags, nil, tags))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"github.com/Tebro/prj/templates"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func loadTemplate(name string) (*templates.Template, error) {
+	dir := filepath.Join(db.GetTemplatesDir(), name)
+	exists, err := pathExists(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("template '%s' does not exist in %s", name, db.GetTemplatesDir())
+	}
+	return templates.Load(dir)
+}
+
+func parseTemplateVars(c *cli.Context) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, v := range c.StringSlice("var") {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid variable '%s', expected key=value", v)
+		}
+		vars[parts[0]] = parts[1]
+	}
+	return vars, nil
+}
+
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
+func resolveTemplateValues(c *cli.Context, t *templates.Template, name string, path string) (map[string]interface{}, error) {
+	given, err := parseTemplateVars(c)
+	if err != nil {
+		return nil, err
+	}
+
+	builtins := map[string]interface{}{
+		"Name":       name,
+		"Path":       path,
+		"Categories": c.StringSlice("categories"),
+	}
+
+	prompter := templates.Prompter{
+		In:          bufio.NewReader(os.Stdin),
+		Out:         c.App.Writer,
+		Interactive: !c.Bool("no-input") && stdinIsTerminal(),
+	}
+
+	return t.Resolve(builtins, given, prompter)
+}
+
+func templateEnv(name string, path string) []string {
+	return []string{
+		"PRJ_NAME=" + name,
+		"PRJ_PATH=" + path,
+	}
+}
+
+func listTemplates(c *cli.Context) error {
+	names, err := templates.List(db.GetTemplatesDir())
+	if err != nil {
+		return exitErrorWrapper("could not list templates: %s", err.Error())
+	}
+
+	type templateRecord struct {
+		Name        string
+		Description string
+		Error       string
+	}
+	records := []templateRecord{}
+	retval := ""
+	for _, name := range names {
+		t, err := templates.Load(filepath.Join(db.GetTemplatesDir(), name))
+		if err != nil {
+			records = append(records, templateRecord{Name: name, Error: err.Error()})
+			retval = fmt.Sprintf("%s%s: (invalid: %s)\n", retval, name, err.Error())
+			continue
+		}
+		records = append(records, templateRecord{Name: name, Description: t.Manifest.Description})
+		retval = fmt.Sprintf("%s%s: %s\n", retval, name, t.Manifest.Description)
+	}
+	if c.String("format") != "" {
+		return writeFormatted(c, records)
+	}
+
+	log(c, `Templates
+---------
+%s`, retval)
+	return nil
+}
+
+func saveTemplate(c *cli.Context) error {
+	if c.NArg() != 2 {
+		return exitErrorWrapper("invalid number of arguments, expected 2")
+	}
+
+	path, err := db.GetProjectDir(c.Args()[0])
+	if err != nil {
+		return exitErrorWrapper("could not save template: %s", err.Error())
+	}
+
+	name := c.Args()[1]
+	if name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return exitErrorWrapper("invalid template name '%s'", name)
+	}
+
+	var replacements []templates.Replacement
+	for _, r := range c.StringSlice("replace") {
+		parts := strings.SplitN(r, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return exitErrorWrapper("invalid replacement '%s', expected literal=Variable", r)
+		}
+		if !templates.ValidVariable(parts[1]) {
+			return exitErrorWrapper("invalid variable name '%s' in replacement '%s', use letters, digits and _", parts[1], r)
+		}
+		replacements = append(replacements, templates.Replacement{Literal: parts[0], Variable: parts[1]})
+	}
+
+	dst := filepath.Join(db.GetTemplatesDir(), name)
+	err = templates.Save(path, dst, c.String("description"), replacements)
+	if err != nil {
+		return exitErrorWrapper("could not save template: %s", err.Error())
+	}
+
+	log(c, "Saved template %s, use it with 'prj new --template %s'", name, name)
+	return nil
+}
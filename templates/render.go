@@ -0,0 +1,189 @@
+package templates
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Render copies the template into dest, which must already exist. Path names
+// and the contents of .tmpl files are rendered with values. Files excluded by
+// a file rule are skipped.
+func (t *Template) Render(dest string, values map[string]interface{}) error {
+	excluded, err := t.excludedPatterns(values)
+	if err != nil {
+		return err
+	}
+
+	return filepath.Walk(t.Dir, func(src string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(t.Dir, src)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		slashed := filepath.ToSlash(rel)
+
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if slashed == ManifestName {
+			return nil
+		}
+		if matchAny(excluded, slashed) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target, err := expandPath(rel, values)
+		if err != nil {
+			return fmt.Errorf("could not render path %s: %s", slashed, err.Error())
+		}
+		target = filepath.Join(dest, target)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(target, Suffix) {
+			target = strings.TrimSuffix(target, Suffix)
+			rendered, err := expand(string(data), values)
+			if err != nil {
+				return fmt.Errorf("could not render %s: %s", slashed, err.Error())
+			}
+			data = []byte(rendered)
+		}
+
+		return ioutil.WriteFile(target, data, info.Mode().Perm())
+	})
+}
+
+// expandPath renders the path rel, which has to stay inside the project
+// after rendering. Values come from the user and could contain ../ or an
+// absolute path.
+func expandPath(rel string, values map[string]interface{}) (string, error) {
+	expanded, err := expand(rel, values)
+	if err != nil {
+		return "", err
+	}
+	expanded = filepath.Clean(expanded)
+	if filepath.IsAbs(expanded) || filepath.VolumeName(expanded) != "" || expanded == ".." || strings.HasPrefix(expanded, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("'%s' is outside of the project", filepath.ToSlash(expanded))
+	}
+	return expanded, nil
+}
+
+// excludedPatterns returns the path patterns of all file rules whose
+// condition does not hold.
+func (t *Template) excludedPatterns(values map[string]interface{}) ([]string, error) {
+	var patterns []string
+	for _, rule := range t.Manifest.Files {
+		ok, err := enabled(rule.When, rule.Unless, values)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			patterns = append(patterns, rule.Paths...)
+		}
+	}
+	return patterns, nil
+}
+
+// RunPost executes the post-render steps of the manifest in dir, writing each
+// command and its output to out. env is appended to the environment of the
+// commands. Execution stops at the first failing step.
+func (t *Template) RunPost(dir string, values map[string]interface{}, env []string, out io.Writer) error {
+	for _, step := range t.Manifest.Post {
+		ok, err := enabled(step.When, step.Unless, values)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		command, err := expand(step.Run, values)
+		if err != nil {
+			return fmt.Errorf("could not render post step '%s': %s", step.Run, err.Error())
+		}
+
+		fmt.Fprintf(out, "$ %s\n", command)
+		cmd := shellCommand(command)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), env...)
+		output, err := cmd.CombinedOutput()
+		if len(output) > 0 {
+			out.Write(output)
+		}
+		if err != nil {
+			return fmt.Errorf("post step '%s' failed: %s", command, err.Error())
+		}
+	}
+	return nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches a slash separated path against a glob pattern in which
+// ** matches any number of path segments. A pattern also matches everything
+// below a matching directory.
+func matchGlob(pattern string, name string) bool {
+	return matchSegments(strings.Split(strings.Trim(pattern, "/"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern []string, name []string) bool {
+	if len(pattern) == 0 {
+		return true
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchSegments(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	ok, err := path.Match(pattern[0], name[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], name[1:])
+}
@@ -0,0 +1,128 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/Tebro/prj/fsutil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Builtins are the variables that are always available when rendering.
+var Builtins = []string{"Name", "Path", "Categories"}
+
+// IsBuiltin reports whether name is a builtin variable.
+func IsBuiltin(name string) bool {
+	for _, b := range Builtins {
+		if b == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Replacement turns every occurrence of Literal into a reference to the
+// template variable Variable.
+type Replacement struct {
+	Literal  string
+	Variable string
+}
+
+var identifier = regexp.MustCompile(`^[\p{L}_][\p{L}\p{Nd}_]*$`)
+
+// ValidVariable reports whether name can be referenced as {{.name}} in a
+// template.
+func ValidVariable(name string) bool {
+	return identifier.MatchString(name)
+}
+
+// Save creates a template in dst from the project directory src. Version
+// control metadata and gitignored files are skipped. Every occurrence of a
+// replacement literal in file contents and path names is parameterised, files
+// that change are given the .tmpl suffix. Non builtin variables are declared
+// in the generated manifest with the literal as their default.
+func Save(src string, dst string, description string, replacements []Replacement) error {
+	exists, err := pathExists(dst)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("template '%s' already exists", dst)
+	}
+
+	sort.Slice(replacements, func(a int, b int) bool {
+		return len(replacements[a].Literal) > len(replacements[b].Literal)
+	})
+
+	pairs := []string{"{{", `{{"{{"}}`}
+	for _, r := range replacements {
+		if r.Literal == "" || r.Variable == "" {
+			return fmt.Errorf("invalid replacement of '%s' with '%s'", r.Literal, r.Variable)
+		}
+		if !ValidVariable(r.Variable) {
+			return fmt.Errorf("invalid variable name '%s'", r.Variable)
+		}
+		pairs = append(pairs, r.Literal, fmt.Sprintf("{{.%s}}", r.Variable))
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	opts := fsutil.CopyOptions{
+		SkipVCS:   true,
+		Gitignore: true,
+		Rename:    replacer.Replace,
+		Rewrite: func(rel string, data []byte) (string, []byte, error) {
+			if bytes.IndexByte(data, 0) >= 0 {
+				// Binary files are copied verbatim
+				return rel, data, nil
+			}
+			replaced := replacer.Replace(string(data))
+			if replaced == string(data) && !strings.HasSuffix(rel, Suffix) {
+				return rel, data, nil
+			}
+			return rel + Suffix, []byte(replaced), nil
+		},
+	}
+
+	if err := fsutil.CopyTree(src, dst, opts); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+
+	if err := writeManifest(filepath.Join(dst, ManifestName), description, replacements); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return nil
+}
+
+func writeManifest(filename string, description string, replacements []Replacement) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "description = %s\n", strconv.Quote(description))
+
+	declared := make(map[string]bool)
+	for _, r := range replacements {
+		if IsBuiltin(r.Variable) || declared[r.Variable] {
+			continue
+		}
+		declared[r.Variable] = true
+		fmt.Fprintf(&buf, "\n[[variables]]\nname = %s\ndefault = %s\n", strconv.Quote(r.Variable), strconv.Quote(r.Literal))
+	}
+
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+func pathExists(path string) (bool, error) {
+	_, err := os.Stat(path)
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return true, err
+}
@@ -0,0 +1,175 @@
+// Package templates implements project templates. A template is a directory
+// whose contents are copied into new projects. Files ending in .tmpl and all
+// path names are rendered with text/template. An optional template.toml
+// manifest declares variables, conditional files and post-render steps.
+package templates
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/toml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestName is the name of the manifest file in the template root. It is
+// never copied into the rendered project.
+const ManifestName = "template.toml"
+
+// Suffix marks files whose contents should be rendered. It is stripped from
+// the rendered file name.
+const Suffix = ".tmpl"
+
+// Variable is a value declared by the manifest that is prompted for or
+// supplied with --var when the template is rendered.
+type Variable struct {
+	Name        string
+	Type        string
+	Description string
+	Default     string
+	Choices     []string
+	Validate    string
+}
+
+// FileRule includes or excludes the files matching Paths depending on a
+// condition. When is a template pipeline, e.g. `.Docker` or
+// `eq .Database "postgres"`; Unless is its negation.
+type FileRule struct {
+	Paths  []string `toml:"paths"`
+	When   string   `toml:"when"`
+	Unless string   `toml:"unless"`
+}
+
+// PostStep is a shell command executed in the new project directory after
+// the template has been rendered.
+type PostStep struct {
+	Run    string `toml:"run"`
+	When   string `toml:"when"`
+	Unless string `toml:"unless"`
+}
+
+// Manifest is the decoded template.toml of a template.
+type Manifest struct {
+	Description string
+	Variables   []Variable
+	Files       []FileRule
+	Post        []PostStep
+}
+
+// Template is a template directory together with its manifest.
+type Template struct {
+	Name     string
+	Dir      string
+	Manifest Manifest
+}
+
+// Load reads the template stored in dir. A missing manifest is not an error,
+// the template is then copied as is.
+func Load(dir string) (*Template, error) {
+	stat, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("template '%s' is not a directory", dir)
+	}
+
+	t := &Template{Name: filepath.Base(dir), Dir: dir}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := parseManifest(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", ManifestName, err.Error())
+	}
+	t.Manifest = manifest
+
+	return t, nil
+}
+
+// manifestFile is the layout of template.toml.
+type manifestFile struct {
+	Description string         `toml:"description"`
+	Variables   []variableFile `toml:"variables"`
+	Files       []FileRule     `toml:"files"`
+	Post        []PostStep     `toml:"post"`
+}
+
+// variableFile is a declared variable, whose default can be of any type.
+type variableFile struct {
+	Name        string      `toml:"name"`
+	Type        string      `toml:"type"`
+	Description string      `toml:"description"`
+	Default     interface{} `toml:"default"`
+	Choices     []string    `toml:"choices"`
+	Validate    string      `toml:"validate"`
+}
+
+func parseManifest(data []byte) (Manifest, error) {
+	var file manifestFile
+	if err := toml.Decode(data, &file); err != nil {
+		return Manifest{}, err
+	}
+
+	m := Manifest{Description: file.Description, Files: file.Files}
+	for _, v := range file.Variables {
+		variable := Variable{
+			Name:        v.Name,
+			Type:        v.Type,
+			Description: v.Description,
+			Choices:     v.Choices,
+			Validate:    v.Validate,
+		}
+		if v.Default != nil {
+			variable.Default = fmt.Sprint(v.Default)
+		}
+		if variable.Name == "" {
+			return m, fmt.Errorf("variable without a name")
+		}
+		if variable.Type == "" {
+			variable.Type = "string"
+		}
+		switch variable.Type {
+		case "string", "bool", "int":
+		default:
+			return m, fmt.Errorf("variable %s has unknown type '%s'", variable.Name, variable.Type)
+		}
+		m.Variables = append(m.Variables, variable)
+	}
+
+	for _, step := range file.Post {
+		if step.Run == "" {
+			return m, fmt.Errorf("post step without a run command")
+		}
+		m.Post = append(m.Post, step)
+	}
+
+	return m, nil
+}
+
+// List returns the names of all templates stored in root.
+func List(root string) ([]string, error) {
+	entries, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
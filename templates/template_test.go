@@ -0,0 +1,131 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	m, err := parseManifest([]byte(`
+description = "service"
+
+[[variables]]
+name = "Docker"
+type = "bool"
+default = false
+
+[[variables]]
+name = "Port"
+type = "int"
+default = 8080
+
+[[files]]
+paths = ["Dockerfile"]
+unless = ".Docker"
+
+[[post]]
+run = "make"
+when = ".Docker"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Manifest{
+		Description: "service",
+		Variables: []Variable{
+			{Name: "Docker", Type: "bool", Default: "false"},
+			{Name: "Port", Type: "int", Default: "8080"},
+		},
+		Files: []FileRule{{Paths: []string{"Dockerfile"}, Unless: ".Docker"}},
+		Post:  []PostStep{{Run: "make", When: ".Docker"}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %+v, want %+v", m, want)
+	}
+}
+
+func TestParseManifestErrors(t *testing.T) {
+	tests := []struct {
+		manifest string
+		want     string
+	}{
+		{"[[files]]\npaths = [\"a\"]\nunles = \".X\"", "unknown key 'files.unles'"},
+		{"[[variables]]\nname = \"X\"\ndefualt = \"x\"", "unknown key 'variables.defualt'"},
+		{"descripton = \"x\"", "unknown key 'descripton'"},
+		{"[[variables]]\ntype = \"bool\"", "variable without a name"},
+		{"[[variables]]\nname = \"X\"\ntype = \"float\"", "unknown type 'float'"},
+		{"[[post]]\nwhen = \".X\"", "post step without a run command"},
+		{"[[files]]\npaths = \"a\"", "into a Go slice"},
+	}
+
+	for _, test := range tests {
+		_, err := parseManifest([]byte(test.manifest))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: got error %v, want it to contain %q", test.manifest, err, test.want)
+		}
+	}
+}
+
+func TestRenderKeepsPathsInside(t *testing.T) {
+	dir := t.TempDir()
+	tmpl := filepath.Join(dir, "tmpl")
+	if err := os.MkdirAll(filepath.Join(tmpl, "{{.Sub}}"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmpl, "{{.Sub}}", "file"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "dest")
+	if err := os.Mkdir(dest, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	template := &Template{Name: "tmpl", Dir: tmpl}
+	for _, sub := range []string{"..", "../escaped", "a/../../escaped", dir} {
+		if err := template.Render(dest, map[string]interface{}{"Sub": sub}); err == nil {
+			t.Errorf("rendering with %q: expected an error", sub)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); err == nil {
+		t.Errorf("a file was written outside of the project")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file")); err == nil {
+		t.Errorf("a file was written outside of the project")
+	}
+
+	if err := template.Render(dest, map[string]interface{}{"Sub": "a/../b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "b", "file")); err != nil {
+		t.Errorf("rendered file missing: %s", err)
+	}
+}
+
+func TestSaveRejectsInvalidVariables(t *testing.T) {
+	src := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(src, "main.go"), []byte("package myapp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, variable := range []string{"my-var", "1st", "a.b", "a b", "{{x}}"} {
+		dst := filepath.Join(t.TempDir(), "tmpl")
+		err := Save(src, dst, "", []Replacement{{Literal: "myapp", Variable: variable}})
+		if err == nil || !strings.Contains(err.Error(), "invalid variable name") {
+			t.Errorf("%q: got error %v, want an invalid variable name", variable, err)
+		}
+		if _, err := os.Stat(dst); err == nil {
+			t.Errorf("%q: the template was written", variable)
+		}
+	}
+
+	for _, variable := range []string{"App", "app_name", "_x1"} {
+		dst := filepath.Join(t.TempDir(), "tmpl")
+		if err := Save(src, dst, "", []Replacement{{Literal: "myapp", Variable: variable}}); err != nil {
+			t.Errorf("%q: %s", variable, err)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package templates
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Prompter asks the user for the values of template variables. When
+// Interactive is false defaults are used without asking, and a variable
+// without a default is an error.
+type Prompter struct {
+	In          *bufio.Reader
+	Out         io.Writer
+	Interactive bool
+}
+
+// Resolve computes the values of all variables declared by the manifest.
+// Values given on the command line take precedence, the remaining variables
+// are prompted for. Builtins (Name, Path, ...) are included in the result and
+// can be referenced by defaults. Given values for undeclared variables are
+// passed through as strings.
+func (t *Template) Resolve(builtins map[string]interface{}, given map[string]string, p Prompter) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for k, v := range builtins {
+		values[k] = v
+	}
+
+	declared := make(map[string]bool)
+	for _, v := range t.Manifest.Variables {
+		declared[v.Name] = true
+	}
+	for k, v := range given {
+		if _, builtin := builtins[k]; builtin {
+			return nil, fmt.Errorf("variable %s is builtin and cannot be set", k)
+		}
+		if !declared[k] {
+			values[k] = v
+		}
+	}
+
+	for _, v := range t.Manifest.Variables {
+		if raw, ok := given[v.Name]; ok {
+			converted, err := v.convert(raw)
+			if err != nil {
+				return nil, err
+			}
+			values[v.Name] = converted
+			continue
+		}
+
+		def, err := expand(v.Default, values)
+		if err != nil {
+			return nil, fmt.Errorf("default of variable %s: %s", v.Name, err.Error())
+		}
+
+		converted, err := p.ask(v, def)
+		if err != nil {
+			return nil, err
+		}
+		values[v.Name] = converted
+	}
+
+	return values, nil
+}
+
+func (p Prompter) ask(v Variable, def string) (interface{}, error) {
+	if !p.Interactive {
+		if def == "" && v.Type == "string" {
+			return nil, fmt.Errorf("variable %s has no default, set it with --var %s=value", v.Name, v.Name)
+		}
+		return v.convert(def)
+	}
+
+	for {
+		fmt.Fprint(p.Out, v.prompt(def))
+		line, err := p.In.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			// Input was closed, fall back to the non-interactive behaviour.
+			fmt.Fprintln(p.Out)
+			p.Interactive = false
+			return p.ask(v, def)
+		}
+
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = def
+		}
+
+		converted, err := v.convert(answer)
+		if err == nil {
+			return converted, nil
+		}
+		fmt.Fprintln(p.Out, err.Error())
+	}
+}
+
+func (v Variable) prompt(def string) string {
+	msg := v.Name
+	if v.Description != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, v.Description)
+	}
+	if len(v.Choices) > 0 {
+		msg = fmt.Sprintf("%s [%s]", msg, strings.Join(v.Choices, "/"))
+	} else if v.Type == "bool" {
+		msg = fmt.Sprintf("%s [true/false]", msg)
+	}
+	if def != "" {
+		msg = fmt.Sprintf("%s (default %s)", msg, def)
+	}
+	return msg + ": "
+}
+
+// convert validates raw and converts it to the declared type of the variable.
+func (v Variable) convert(raw string) (interface{}, error) {
+	if len(v.Choices) > 0 {
+		valid := false
+		for _, c := range v.Choices {
+			if c == raw {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid value '%s' for %s, expected one of: %s", raw, v.Name, strings.Join(v.Choices, ", "))
+		}
+	}
+
+	if v.Validate != "" {
+		re, err := regexp.Compile(v.Validate)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s has an invalid validate pattern: %s", v.Name, err.Error())
+		}
+		if !re.MatchString(raw) {
+			return nil, fmt.Errorf("invalid value '%s' for %s, must match %s", raw, v.Name, v.Validate)
+		}
+	}
+
+	switch v.Type {
+	case "bool":
+		if raw == "" {
+			return false, nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s, expected true or false", raw, v.Name)
+		}
+		return b, nil
+	case "int":
+		if raw == "" {
+			return 0, nil
+		}
+		i, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for %s, expected an integer", raw, v.Name)
+		}
+		return i, nil
+	}
+
+	return raw, nil
+}
+
+// expand renders text as a template using values.
+func expand(text string, values map[string]interface{}) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+
+	tmpl, err := template.New("").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// condition evaluates a template pipeline such as `.Docker` or
+// `eq .Database "postgres"` for truthiness.
+func condition(pipeline string, values map[string]interface{}) (bool, error) {
+	out, err := expand(fmt.Sprintf("{{if %s}}true{{end}}", pipeline), values)
+	if err != nil {
+		return false, fmt.Errorf("invalid condition '%s': %s", pipeline, err.Error())
+	}
+	return out == "true", nil
+}
+
+// enabled reports whether a when/unless pair holds for values.
+func enabled(when string, unless string, values map[string]interface{}) (bool, error) {
+	if when != "" {
+		ok, err := condition(when, values)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if unless != "" {
+		ok, err := condition(unless, values)
+		if err != nil || ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,103 @@
+// Package toml decodes the TOML documents used by prj manifests and templates,
+// either strictly into structs or into nested maps with helpers to read
+// values from them. Parsing is done by github.com/BurntSushi/toml.
+package toml
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+)
+
+// Decode decodes a TOML document into v, which has to be a pointer to a
+// struct with toml tags. Keys v has no field for are an error, so a
+// misspelled key isn't silently ignored.
+func Decode(data []byte, v interface{}) error {
+	md, err := toml.Decode(string(data), v)
+	if err != nil {
+		return err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf("unknown key '%s'", undecoded[0].String())
+	}
+	return nil
+}
+
+// Parse decodes a TOML document into nested maps. Tables become
+// map[string]interface{} values, arrays of tables become
+// []map[string]interface{} values and arrays of strings become []string
+// values.
+func Parse(data []byte) (map[string]interface{}, error) {
+	doc := make(map[string]interface{})
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, err
+	}
+	normalizeTable(doc)
+	return doc, nil
+}
+
+func normalizeTable(table map[string]interface{}) {
+	for key, value := range table {
+		table[key] = normalize(value)
+	}
+}
+
+// normalize converts arrays with only string elements into []string, which is
+// what callers almost always want, in value and everything nested in it.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		normalizeTable(v)
+	case []map[string]interface{}:
+		for _, table := range v {
+			normalizeTable(table)
+		}
+	case []interface{}:
+		strs := make([]string, 0, len(v))
+		for i, element := range v {
+			v[i] = normalize(element)
+			if s, ok := v[i].(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		if len(strs) == len(v) {
+			return strs
+		}
+	}
+	return value
+}
+
+// String returns the string stored under key, or the empty string.
+func String(table map[string]interface{}, key string) string {
+	s, _ := table[key].(string)
+	return s
+}
+
+// Strings returns the string array stored under key. A single string value is
+// returned as a one element slice.
+func Strings(table map[string]interface{}, key string) []string {
+	switch v := table[key].(type) {
+	case []string:
+		return v
+	case string:
+		return []string{v}
+	}
+	return nil
+}
+
+// Bool returns the boolean stored under key, or false.
+func Bool(table map[string]interface{}, key string) bool {
+	b, _ := table[key].(bool)
+	return b
+}
+
+// Table returns the table stored under key, or nil.
+func Table(table map[string]interface{}, key string) map[string]interface{} {
+	t, _ := table[key].(map[string]interface{})
+	return t
+}
+
+// Tables returns the array of tables stored under key, or nil.
+func Tables(table map[string]interface{}, key string) []map[string]interface{} {
+	t, _ := table[key].([]map[string]interface{})
+	return t
+}
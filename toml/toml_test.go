@@ -0,0 +1,96 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want map[string]interface{}
+	}{
+		{
+			name: "strings and escapes",
+			doc:  "a = \"tab\\there\"\nb = 'C:\\dir'\nc = \"\"\"\nline\"\"\"",
+			want: map[string]interface{}{"a": "tab\there", "b": `C:\dir`, "c": "line"},
+		},
+		{
+			name: "numbers",
+			doc:  "a = 10\nb = 0x1f\nc = 1_000\nd = 0.5\ne = 0",
+			want: map[string]interface{}{"a": int64(10), "b": int64(31), "c": int64(1000), "d": 0.5, "e": int64(0)},
+		},
+		{
+			name: "string arrays",
+			doc:  "a = [\"x\", \"y\"]\nb = []\nc = [1, 2]",
+			want: map[string]interface{}{"a": []string{"x", "y"}, "b": []string{}, "c": []interface{}{int64(1), int64(2)}},
+		},
+		{
+			name: "dotted keys and tables",
+			doc:  "a.b = 1\n[hooks]\npost-new = [\"make\"]",
+			want: map[string]interface{}{
+				"a":     map[string]interface{}{"b": int64(1)},
+				"hooks": map[string]interface{}{"post-new": []string{"make"}},
+			},
+		},
+		{
+			name: "arrays of tables",
+			doc:  "[[post]]\nrun = \"a\"\n[[post]]\nrun = \"b\"\nwhen = [\"x\"]",
+			want: map[string]interface{}{"post": []map[string]interface{}{
+				{"run": "a"},
+				{"run": "b", "when": []string{"x"}},
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		got, err := Parse([]byte(test.doc))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"octal looking integer", "a = 0123"},
+		{"leading zero", "a = 08"},
+		{"duplicate key", "a = 1\na = 2"},
+		{"duplicate table", "[a]\n[a]"},
+		{"unterminated string", "a = \"x"},
+		{"missing value", "a ="},
+	}
+
+	for _, test := range tests {
+		if _, err := Parse([]byte(test.doc)); err == nil {
+			t.Errorf("%s: expected an error for %q", test.name, test.doc)
+		}
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	doc, err := Parse([]byte("s = \"x\"\nb = true\nlist = [\"y\"]\n[t]\n[[ts]]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if String(doc, "s") != "x" || String(doc, "b") != "" {
+		t.Errorf("String returned the wrong values")
+	}
+	if !reflect.DeepEqual(Strings(doc, "s"), []string{"x"}) || !reflect.DeepEqual(Strings(doc, "list"), []string{"y"}) {
+		t.Errorf("Strings returned the wrong values")
+	}
+	if !Bool(doc, "b") || Bool(doc, "s") {
+		t.Errorf("Bool returned the wrong values")
+	}
+	if Table(doc, "t") == nil || len(Tables(doc, "ts")) != 1 {
+		t.Errorf("Table or Tables did not find the tables")
+	}
+}
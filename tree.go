@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"github.com/Tebro/prj/db"
+	"gopkg.in/urfave/cli.v1"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// treeStyle holds the prefixes used to draw a tree.
+type treeStyle struct {
+	branch, last, pipe, space string
+}
+
+var treeStyles = map[string]treeStyle{
+	"unicode": {"├── ", "└── ", "│   ", "    "},
+	"ascii":   {"|-- ", "`-- ", "|   ", "    "},
+}
+
+// categoryNode is a category directory with its subcategories and the
+// projects directly inside it.
+type categoryNode struct {
+	name       string
+	categories map[string]*categoryNode
+	projects   []db.Project
+}
+
+func newCategoryNode(name string) *categoryNode {
+	return &categoryNode{name: name, categories: make(map[string]*categoryNode)}
+}
+
+func (n *categoryNode) add(categories []string, p db.Project) {
+	if len(categories) == 0 {
+		n.projects = append(n.projects, p)
+		return
+	}
+	child, ok := n.categories[categories[0]]
+	if !ok {
+		child = newCategoryNode(categories[0])
+		n.categories[categories[0]] = child
+	}
+	child.add(categories[1:], p)
+}
+
+// count returns the number of projects in the category and all its
+// subcategories.
+func (n *categoryNode) count() int {
+	count := len(n.projects)
+	for _, child := range n.categories {
+		count += child.count()
+	}
+	return count
+}
+
+type treePrinter struct {
+	w     io.Writer
+	style treeStyle
+	depth int
+}
+
+// print writes the children of n, prefix is the drawing for the levels above.
+func (t treePrinter) print(n *categoryNode, prefix string, level int) {
+	var names []string
+	for name := range n.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Slice(n.projects, func(a int, b int) bool {
+		return n.projects[a].Name < n.projects[b].Name
+	})
+
+	total := len(names) + len(n.projects)
+	i := 0
+	next := func() (string, string) {
+		i++
+		if i == total {
+			return prefix + t.style.last, prefix + t.style.space
+		}
+		return prefix + t.style.branch, prefix + t.style.pipe
+	}
+
+	for _, name := range names {
+		child := n.categories[name]
+		line, childPrefix := next()
+		fmt.Fprintf(t.w, "%s%s/ (%d)\n", line, name, child.count())
+		if t.depth == 0 || level < t.depth {
+			t.print(child, childPrefix, level+1)
+		}
+	}
+	for _, p := range n.projects {
+		line, _ := next()
+		fmt.Fprintf(t.w, "%s%s\n", line, projectLabel(p))
+	}
+}
+
+func projectLabel(p db.Project) string {
+	if p.Archived {
+		return p.Name + " (archived)"
+	}
+	return p.Name
+}
+
+func printProjectTree(c *cli.Context, projects []db.Project) error {
+	style, ok := treeStyles[c.String("style")]
+	if !ok {
+		return exitErrorWrapper("unknown style '%s', expected unicode or ascii", c.String("style"))
+	}
+	printer := treePrinter{w: c.App.Writer, style: style, depth: c.Int("depth")}
+
+	var roots []*categoryNode
+	outside := newCategoryNode("")
+	for _, dir := range getBaseDirs(c) {
+		roots = append(roots, newCategoryNode(absPath(dir)))
+	}
+
+	for _, p := range projects {
+		path := absPath(p.Path)
+		added := false
+		for _, root := range roots {
+			if !isBelow(path, root.name) {
+				continue
+			}
+			rel, _ := filepath.Rel(root.name, filepath.Dir(path))
+			var categories []string
+			if rel != "." {
+				categories = strings.Split(filepath.ToSlash(rel), "/")
+			}
+			root.add(categories, p)
+			added = true
+			break
+		}
+		if !added {
+			outside.add(nil, p)
+		}
+	}
+
+	for i, root := range roots {
+		// Extra base dirs are only shown when they contain projects.
+		if i > 0 && root.count() == 0 {
+			continue
+		}
+		log(c, "%s (%d)", root.name, root.count())
+		printer.print(root, "", 1)
+	}
+	if len(outside.projects) > 0 {
+		log(c, "Outside of the base dirs (%d)", len(outside.projects))
+		sort.Slice(outside.projects, func(a int, b int) bool {
+			return outside.projects[a].Name < outside.projects[b].Name
+		})
+		for i, p := range outside.projects {
+			line := style.branch
+			if i == len(outside.projects)-1 {
+				line = style.last
+			}
+			log(c, "%s%s: %s", line, projectLabel(p), p.Path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package vcs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fossilRepository is the name of the repository file prj keeps next to the
+// checkout of a Fossil project.
+const fossilRepository = ".fossil"
+
+// fossilSeparator separates the fields of the timeline format, Fossil has no
+// escape for NUL.
+const fossilSeparator = "\x1f"
+
+// Fossil drives fossil. Fossil keeps the repository in a single file, prj
+// stores it as .fossil in the project directory.
+type Fossil struct{}
+
+// Name implements VCS.
+func (Fossil) Name() string {
+	return "fossil"
+}
+
+// Command implements VCS.
+func (Fossil) Command() string {
+	return "fossil"
+}
+
+// Detect implements VCS.
+func (Fossil) Detect(dir string) bool {
+	return exists(filepath.Join(dir, ".fslckout")) || exists(filepath.Join(dir, "_FOSSIL_"))
+}
+
+// Init implements VCS. Fossil always starts on trunk, branch is ignored.
+func (Fossil) Init(dir string, branch string) error {
+	if _, err := run(dir, "fossil", "init", fossilRepository); err != nil {
+		return err
+	}
+	_, err := run(dir, "fossil", "open", "--force", fossilRepository)
+	return err
+}
+
+// Clone implements VCS. Fossil does not support shallow clones.
+func (Fossil) Clone(url string, dir string, opts CloneOptions) error {
+	if opts.Depth > 0 {
+		return fmt.Errorf("fossil does not support shallow clones")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := runAttached(dir, opts.Stdout, opts.Stderr, "fossil", "clone", url, fossilRepository); err != nil {
+		return err
+	}
+	args := []string{"open", "--force", fossilRepository}
+	if opts.Branch != "" {
+		args = append(args, opts.Branch)
+	}
+	return runAttached(dir, opts.Stdout, opts.Stderr, "fossil", args...)
+}
+
+// CurrentBranch implements VCS.
+func (Fossil) CurrentBranch(dir string) (string, error) {
+	return run(dir, "fossil", "branch", "current")
+}
+
+// IsDirty implements VCS.
+func (Fossil) IsDirty(dir string) (bool, error) {
+	out, err := run(dir, "fossil", "changes")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Status implements VCS. Fossil has no staging area, all changes to tracked
+// files count as unstaged.
+func (Fossil) Status(dir string) (Status, error) {
+	out, err := run(dir, "fossil", "changes")
+	if err != nil {
+		return Status{}, err
+	}
+
+	var s Status
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "CONFLICT"):
+			s.Conflicts++
+		case line != "":
+			s.Unstaged++
+		}
+	}
+	if extras, err := run(dir, "fossil", "extras"); err == nil {
+		s.Untracked = countLines(extras)
+	}
+	if s.Branch, err = run(dir, "fossil", "branch", "current"); err != nil {
+		return Status{}, err
+	}
+	if stashes, err := run(dir, "fossil", "stash", "list"); err == nil {
+		for _, line := range strings.Split(stashes, "\n") {
+			if fields := strings.Fields(line); len(fields) > 0 && strings.HasSuffix(fields[0], ":") {
+				s.Stashes++
+			}
+		}
+	}
+	return s, nil
+}
+
+// LastCommit implements VCS.
+func (Fossil) LastCommit(dir string) (Commit, error) {
+	format := strings.Join([]string{"%H", "%a", "", "%d", "%c"}, fossilSeparator)
+	out, err := run(dir, "fossil", "timeline", "current", "-n", "1", "-t", "ci", "-F", format)
+	if err != nil {
+		return Commit{}, err
+	}
+	line := strings.SplitN(out, "\n", 2)[0]
+	return parseCommit(strings.Replace(line, fossilSeparator, "\x00", -1), "2006-01-02 15:04:05")
+}
+
+// RootCommit implements VCS. Fossil has a project code shared by all clones,
+// which serves the same purpose.
+func (Fossil) RootCommit(dir string) (string, error) {
+	out, err := run(dir, "fossil", "info")
+	if err != nil {
+		return "", err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "project-code:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "project-code:")), nil
+		}
+	}
+	return "", fmt.Errorf("no project code")
+}
+
+// Remotes implements VCS.
+func (Fossil) Remotes(dir string) ([]Remote, error) {
+	out, err := run(dir, "fossil", "remote")
+	if err != nil {
+		return nil, err
+	}
+	if out == "" || out == "off" {
+		return nil, nil
+	}
+	return []Remote{{Name: "default", URL: out}}, nil
+}
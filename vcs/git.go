@@ -0,0 +1,211 @@
+package vcs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Git drives git.
+type Git struct{}
+
+// Name implements VCS.
+func (Git) Name() string {
+	return "git"
+}
+
+// Command implements VCS.
+func (Git) Command() string {
+	return "git"
+}
+
+// Detect implements VCS. .git is a file in worktrees and submodules.
+func (Git) Detect(dir string) bool {
+	return exists(filepath.Join(dir, ".git"))
+}
+
+// Init implements VCS.
+func (Git) Init(dir string, branch string) error {
+	if _, err := run(dir, "git", "init"); err != nil {
+		return err
+	}
+	if branch == "" {
+		return nil
+	}
+	_, err := run(dir, "git", "symbolic-ref", "HEAD", "refs/heads/"+branch)
+	return err
+}
+
+// Clone implements VCS.
+func (Git) Clone(url string, dir string, opts CloneOptions) error {
+	args := []string{"clone"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch)
+	}
+	args = append(args, url, dir)
+	return runAttached("", opts.Stdout, opts.Stderr, "git", args...)
+}
+
+// CurrentBranch implements VCS. A detached HEAD is reported as
+// "(detached <hash>)".
+func (Git) CurrentBranch(dir string) (string, error) {
+	branch, err := run(dir, "git", "symbolic-ref", "--short", "-q", "HEAD")
+	if err == nil {
+		return branch, nil
+	}
+	hash, err := run(dir, "git", "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return "(detached " + hash + ")", nil
+}
+
+// IsDirty implements VCS.
+func (Git) IsDirty(dir string) (bool, error) {
+	out, err := run(dir, "git", "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Status implements VCS.
+func (g Git) Status(dir string) (Status, error) {
+	return g.StatusContext(context.Background(), dir)
+}
+
+// StatusContext is Status with a context that can stop the git commands.
+func (Git) StatusContext(ctx context.Context, dir string) (Status, error) {
+	out, err := runContext(ctx, dir, "git", "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Status{}, err
+	}
+
+	var s Status
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "#":
+			parseGitBranchHeader(&s, fields[1:])
+		case "1", "2":
+			if xy := fields[1]; len(xy) == 2 {
+				if xy[0] != '.' {
+					s.Staged++
+				}
+				if xy[1] != '.' {
+					s.Unstaged++
+				}
+			}
+		case "u":
+			s.Conflicts++
+		case "?":
+			s.Untracked++
+		}
+	}
+
+	// Without stashes refs/stash does not exist and the command fails.
+	if stashes, err := runContext(ctx, dir, "git", "stash", "list"); err == nil {
+		s.Stashes = countLines(stashes)
+	}
+	return s, nil
+}
+
+// parseGitBranchHeader reads a "# branch.<key> <value>" line of git status
+// --porcelain=v2 --branch, without the #.
+func parseGitBranchHeader(s *Status, fields []string) {
+	if len(fields) < 2 {
+		return
+	}
+	switch fields[0] {
+	case "branch.oid":
+		if fields[1] != "(initial)" && len(fields[1]) >= 7 {
+			s.Head = fields[1][:7]
+		}
+	case "branch.head":
+		if fields[1] == "(detached)" {
+			s.Detached = true
+		} else {
+			s.Branch = fields[1]
+		}
+	case "branch.upstream":
+		s.Upstream = fields[1]
+	case "branch.ab":
+		if len(fields) == 3 {
+			s.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[1], "+"))
+			s.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "-"))
+		}
+	}
+}
+
+// Fetch updates the remote tracking branches of all remotes. Neither git nor
+// ssh are allowed to ask for credentials, as nobody might be there to answer.
+func (Git) Fetch(ctx context.Context, dir string) error {
+	env := []string{"GIT_TERMINAL_PROMPT=0", "GIT_SSH_COMMAND=" + batchSSHCommand(dir)}
+	_, err := runEnv(ctx, dir, env, "git", "-c", "core.askPass=true", "fetch", "--all", "--quiet")
+	return err
+}
+
+// batchSSHCommand returns the ssh command git uses in dir, with the prompts
+// for passwords and host keys turned off. GIT_SSH_COMMAND takes precedence
+// over core.sshCommand, so a configured command has to be carried over.
+func batchSSHCommand(dir string) string {
+	command := os.Getenv("GIT_SSH_COMMAND")
+	if command == "" {
+		command, _ = run(dir, "git", "config", "core.sshCommand")
+	}
+	if command == "" {
+		command = "ssh"
+	}
+	return command + " -o BatchMode=yes"
+}
+
+// FastForward moves the checked out branch to its upstream, failing if that
+// is not a fast-forward.
+func (Git) FastForward(ctx context.Context, dir string) error {
+	_, err := runContext(ctx, dir, "git", "merge", "--ff-only", "--quiet", "@{upstream}")
+	return err
+}
+
+// LastCommit implements VCS.
+func (Git) LastCommit(dir string) (Commit, error) {
+	out, err := run(dir, "git", "log", "-1", "--format=%H%x00%an%x00%ae%x00%aI%x00%s")
+	if err != nil {
+		return Commit{}, err
+	}
+	return parseCommit(out, time.RFC3339)
+}
+
+// RootCommit implements VCS.
+func (Git) RootCommit(dir string) (string, error) {
+	out, err := run(dir, "git", "rev-list", "--max-parents=0", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return firstLine(out)
+}
+
+// Remotes implements VCS.
+func (Git) Remotes(dir string) ([]Remote, error) {
+	out, err := run(dir, "git", "remote", "-v")
+	if err != nil {
+		return nil, err
+	}
+
+	var remotes []Remote
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[2] == "(fetch)" {
+			remotes = append(remotes, Remote{Name: fields[0], URL: fields[1]})
+		}
+	}
+	return remotes, nil
+}
@@ -0,0 +1,139 @@
+package vcs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mercurial drives hg.
+type Mercurial struct{}
+
+// Name implements VCS.
+func (Mercurial) Name() string {
+	return "hg"
+}
+
+// Command implements VCS.
+func (Mercurial) Command() string {
+	return "hg"
+}
+
+// Detect implements VCS.
+func (Mercurial) Detect(dir string) bool {
+	return isDir(filepath.Join(dir, ".hg"))
+}
+
+// Init implements VCS. The initial branch becomes a named branch.
+func (Mercurial) Init(dir string, branch string) error {
+	if _, err := run(dir, "hg", "init"); err != nil {
+		return err
+	}
+	if branch == "" || branch == "default" {
+		return nil
+	}
+	_, err := run(dir, "hg", "branch", branch)
+	return err
+}
+
+// Clone implements VCS. Mercurial does not support shallow clones.
+func (Mercurial) Clone(url string, dir string, opts CloneOptions) error {
+	if opts.Depth > 0 {
+		return fmt.Errorf("hg does not support shallow clones")
+	}
+	args := []string{"clone"}
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch)
+	}
+	args = append(args, url, dir)
+	return runAttached("", opts.Stdout, opts.Stderr, "hg", args...)
+}
+
+// CurrentBranch implements VCS.
+func (Mercurial) CurrentBranch(dir string) (string, error) {
+	return run(dir, "hg", "branch")
+}
+
+// IsDirty implements VCS.
+func (Mercurial) IsDirty(dir string) (bool, error) {
+	out, err := run(dir, "hg", "status")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Status implements VCS. Mercurial has no staging area, all changes to
+// tracked files count as unstaged.
+func (Mercurial) Status(dir string) (Status, error) {
+	out, err := run(dir, "hg", "status")
+	if err != nil {
+		return Status{}, err
+	}
+
+	var s Status
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "? "):
+			s.Untracked++
+		case line != "":
+			s.Unstaged++
+		}
+	}
+	if s.Branch, err = run(dir, "hg", "branch"); err != nil {
+		return Status{}, err
+	}
+	s.Head, _ = run(dir, "hg", "log", "-r", ".", "--template", "{node|short}")
+	if conflicts, err := run(dir, "hg", "resolve", "--list"); err == nil {
+		for _, line := range strings.Split(conflicts, "\n") {
+			if strings.HasPrefix(line, "U ") {
+				s.Conflicts++
+			}
+		}
+	}
+	// shelve is an extension that may not be enabled.
+	if shelves, err := run(dir, "hg", "shelve", "--list"); err == nil {
+		s.Stashes = countLines(shelves)
+	}
+	return s, nil
+}
+
+// LastCommit implements VCS.
+func (Mercurial) LastCommit(dir string) (Commit, error) {
+	out, err := run(dir, "hg", "log", "-r", ".", "--template",
+		`{node}\0{person(author)}\0{email(author)}\0{date|rfc3339date}\0{desc|firstline}`)
+	if err != nil {
+		return Commit{}, err
+	}
+	if strings.HasPrefix(out, "0000000000000000000000000000000000000000") {
+		return Commit{}, fmt.Errorf("no commits")
+	}
+	return parseCommit(out, time.RFC3339)
+}
+
+// RootCommit implements VCS.
+func (Mercurial) RootCommit(dir string) (string, error) {
+	out, err := run(dir, "hg", "log", "-r", "roots(all())", "--template", `{node}\n`)
+	if err != nil {
+		return "", err
+	}
+	return firstLine(out)
+}
+
+// Remotes implements VCS.
+func (Mercurial) Remotes(dir string) ([]Remote, error) {
+	out, err := run(dir, "hg", "paths")
+	if err != nil {
+		return nil, err
+	}
+
+	var remotes []Remote
+	for _, line := range strings.Split(out, "\n") {
+		parts := strings.SplitN(line, " = ", 2)
+		if len(parts) == 2 {
+			remotes = append(remotes, Remote{Name: parts[0], URL: parts[1]})
+		}
+	}
+	return remotes, nil
+}
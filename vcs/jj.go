@@ -0,0 +1,119 @@
+package vcs
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jujutsu drives jj with the git backend.
+type Jujutsu struct{}
+
+// Name implements VCS.
+func (Jujutsu) Name() string {
+	return "jj"
+}
+
+// Command implements VCS.
+func (Jujutsu) Command() string {
+	return "jj"
+}
+
+// Detect implements VCS.
+func (Jujutsu) Detect(dir string) bool {
+	return isDir(filepath.Join(dir, ".jj"))
+}
+
+// Init implements VCS. The initial branch becomes a bookmark once there is
+// something to point it at, so it is ignored here.
+func (Jujutsu) Init(dir string, branch string) error {
+	_, err := run(dir, "jj", "git", "init")
+	return err
+}
+
+// Clone implements VCS.
+func (Jujutsu) Clone(url string, dir string, opts CloneOptions) error {
+	args := []string{"git", "clone"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.Branch != "" {
+		args = append(args, "--branch", opts.Branch)
+	}
+	args = append(args, url, dir)
+	return runAttached("", opts.Stdout, opts.Stderr, "jj", args...)
+}
+
+// CurrentBranch implements VCS. jj has no checked out branch, the bookmarks
+// of the parent of the working copy commit are reported instead.
+func (Jujutsu) CurrentBranch(dir string) (string, error) {
+	return run(dir, "jj", "log", "--no-graph", "-r", "@-", "-T", `bookmarks.join(",")`)
+}
+
+// IsDirty implements VCS. The working copy is dirty when its commit is not
+// empty.
+func (Jujutsu) IsDirty(dir string) (bool, error) {
+	out, err := run(dir, "jj", "log", "--no-graph", "-r", "@", "-T", "empty")
+	if err != nil {
+		return false, err
+	}
+	return out == "false", nil
+}
+
+// Status implements VCS. jj tracks new files automatically and has no
+// staging area, every change in the working copy commit counts as unstaged.
+func (j Jujutsu) Status(dir string) (Status, error) {
+	out, err := run(dir, "jj", "diff", "--summary", "-r", "@")
+	if err != nil {
+		return Status{}, err
+	}
+
+	s := Status{Unstaged: countLines(out)}
+	if s.Branch, err = j.CurrentBranch(dir); err != nil {
+		return Status{}, err
+	}
+	s.Detached = s.Branch == ""
+	s.Head, _ = run(dir, "jj", "log", "--no-graph", "-r", "@-", "-T", "commit_id.short(7)")
+	if conflict, err := run(dir, "jj", "log", "--no-graph", "-r", "@", "-T", "conflict"); err == nil && conflict == "true" {
+		s.Conflicts = 1
+	}
+	return s, nil
+}
+
+// LastCommit implements VCS.
+func (Jujutsu) LastCommit(dir string) (Commit, error) {
+	template := `commit_id ++ "\0" ++ author.name() ++ "\0" ++ author.email() ++ "\0" ++ ` +
+		`author.timestamp().format("%Y-%m-%dT%H:%M:%S%:z") ++ "\0" ++ description.first_line()`
+	out, err := run(dir, "jj", "log", "--no-graph", "-r", "@-", "-T", template)
+	if err != nil {
+		return Commit{}, err
+	}
+	return parseCommit(out, time.RFC3339)
+}
+
+// RootCommit implements VCS.
+func (Jujutsu) RootCommit(dir string) (string, error) {
+	out, err := run(dir, "jj", "log", "--no-graph", "-r", "root()+ & ::@", "-T", `commit_id ++ "\n"`)
+	if err != nil {
+		return "", err
+	}
+	return firstLine(out)
+}
+
+// Remotes implements VCS.
+func (Jujutsu) Remotes(dir string) ([]Remote, error) {
+	out, err := run(dir, "jj", "git", "remote", "list")
+	if err != nil {
+		return nil, err
+	}
+
+	var remotes []Remote
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			remotes = append(remotes, Remote{Name: fields[0], URL: fields[1]})
+		}
+	}
+	return remotes, nil
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+// +build !windows
+
+package vcs
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killTree makes cmd start its own process group and stops the whole group
+// when its context is done, including the children it started. The group
+// doesn't get the signals of the terminal, the caller has to cancel the
+// context on an interrupt.
+func killTree(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+// +build windows
+
+package vcs
+
+import "os/exec"
+
+// killTree leaves cmd as it is, killing a command only stops the command
+// itself. Children that survive it are cut off by the WaitDelay.
+func killTree(cmd *exec.Cmd) {}
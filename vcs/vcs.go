@@ -0,0 +1,255 @@
+// Package vcs abstracts over the version control systems prj can work with.
+// Each system is implemented by shelling out to its command line tool.
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Commit describes a single commit.
+type Commit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Date    time.Time
+	Subject string
+}
+
+// Remote is a named remote repository.
+type Remote struct {
+	Name string
+	URL  string
+}
+
+// Status is the state of a working copy. Counts a system has no concept of
+// stay zero, e.g. Mercurial has no staging area and only git tracks how far
+// a branch is ahead of or behind its upstream without contacting it.
+type Status struct {
+	// Branch is the checked out branch, empty if Detached.
+	Branch   string
+	Detached bool
+	// Head is the short id of the checked out commit.
+	Head     string
+	Upstream string
+	Ahead    int
+	Behind   int
+	// Staged, Unstaged and Untracked count files, Conflicts counts files
+	// with unresolved merge conflicts.
+	Staged    int
+	Unstaged  int
+	Untracked int
+	Conflicts int
+	Stashes   int
+}
+
+// Dirty reports whether the working copy has changes that are not committed.
+func (s Status) Dirty() bool {
+	return s.Staged+s.Unstaged+s.Untracked+s.Conflicts > 0
+}
+
+// CloneOptions modify how a repository is cloned. Not every system supports
+// every option.
+type CloneOptions struct {
+	Depth  int
+	Branch string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// VCS is a version control system.
+type VCS interface {
+	// Name is the short name of the system, e.g. git or hg.
+	Name() string
+	// Command is the executable used to drive the system.
+	Command() string
+	// Detect reports whether dir is the root of a working copy.
+	Detect(dir string) bool
+	// Init creates a repository in dir, with branch as the initial branch
+	// if the system supports it and branch is not empty.
+	Init(dir string, branch string) error
+	// Clone checks out url into dir, which must not exist.
+	Clone(url string, dir string, opts CloneOptions) error
+	// CurrentBranch returns the checked out branch.
+	CurrentBranch(dir string) (string, error)
+	// IsDirty reports whether the working copy has uncommitted changes.
+	IsDirty(dir string) (bool, error)
+	// Status describes the working copy in detail.
+	Status(dir string) (Status, error)
+	// LastCommit returns the commit the working copy is based on.
+	LastCommit(dir string) (Commit, error)
+	// RootCommit returns an id that stays the same for every clone of the
+	// repository, usually the hash of its first commit.
+	RootCommit(dir string) (string, error)
+	// Remotes returns the configured remote repositories.
+	Remotes(dir string) ([]Remote, error)
+}
+
+// Systems lists all supported systems in detection order. Jujutsu comes
+// before git because colocated jj repositories also contain a .git.
+var Systems = []VCS{Jujutsu{}, Git{}, Mercurial{}, Fossil{}}
+
+var aliases = map[string]string{
+	"mercurial": "hg",
+	"jujutsu":   "jj",
+}
+
+// Get returns the system with the given name.
+func Get(name string) (VCS, error) {
+	name = strings.ToLower(name)
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	for _, v := range Systems {
+		if v.Name() == name {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown version control system '%s', supported: %s", name, strings.Join(Names(), ", "))
+}
+
+// Names returns the names of all supported systems.
+func Names() []string {
+	var names []string
+	for _, v := range Systems {
+		names = append(names, v.Name())
+	}
+	return names
+}
+
+// Detect returns the system managing the working copy rooted at dir.
+func Detect(dir string) (VCS, bool) {
+	for _, v := range Systems {
+		if v.Detect(dir) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Available returns an error if the executable of v cannot be found.
+func Available(v VCS) error {
+	if _, err := exec.LookPath(v.Command()); err != nil {
+		return fmt.Errorf("%s is not installed or not in PATH", v.Command())
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func isDir(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && stat.IsDir()
+}
+
+// run executes name with args in dir and returns its trimmed stdout.
+func run(dir string, name string, args ...string) (string, error) {
+	return runContext(context.Background(), dir, name, args...)
+}
+
+// killDelay is how long a stopped command may keep its output open, in case
+// children it started, like the ssh of a git fetch, survive the kill.
+const killDelay = 3 * time.Second
+
+// runContext is run with a context that can stop the command.
+func runContext(ctx context.Context, dir string, name string, args ...string) (string, error) {
+	return runEnv(ctx, dir, nil, name, args...)
+}
+
+// runEnv is runContext with env added to the environment of the command.
+func runEnv(ctx context.Context, dir string, env []string, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	if env != nil {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if ctx.Done() != nil {
+		killTree(cmd)
+		cmd.WaitDelay = killDelay
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return "", fmt.Errorf("%s %s timed out", name, strings.Join(args, " "))
+		case context.Canceled:
+			return "", fmt.Errorf("%s %s was interrupted", name, strings.Join(args, " "))
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("%s %s failed: %s", name, strings.Join(args, " "), msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// runAttached executes name with args, forwarding its output.
+func runAttached(dir string, stdout io.Writer, stderr io.Writer, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %s", name, strings.Join(args, " "), err.Error())
+	}
+	return nil
+}
+
+// countLines returns the number of non-empty lines in out.
+func countLines(out string) int {
+	count := 0
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// firstLine returns the smallest of the non-empty lines in out, so
+// repositories with several root commits get a stable id.
+func firstLine(out string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("no commits")
+	}
+	sort.Strings(lines)
+	return lines[0], nil
+}
+
+// parseCommit splits a NUL separated hash, author, email, date and subject.
+func parseCommit(out string, layout string) (Commit, error) {
+	fields := strings.SplitN(out, "\x00", 5)
+	if len(fields) != 5 {
+		return Commit{}, fmt.Errorf("no commits")
+	}
+	date, err := time.Parse(layout, strings.TrimSpace(fields[3]))
+	if err != nil {
+		return Commit{}, fmt.Errorf("could not parse commit date '%s'", fields[3])
+	}
+	return Commit{
+		Hash:    fields[0],
+		Author:  fields[1],
+		Email:   fields[2],
+		Date:    date,
+		Subject: fields[4],
+	}, nil
+}
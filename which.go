@@ -0,0 +1,125 @@
+package main
+
+import (
+	"github.com/Tebro/prj/db"
+	"gopkg.in/urfave/cli.v1"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolvePath returns path made absolute with symlinks resolved, or just made
+// absolute if it can't be resolved.
+func resolvePath(path string) string {
+	abs := absPath(path)
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved
+	}
+	return abs
+}
+
+// projectContaining returns the registered project with the longest path that
+// contains path, together with path relative to the project directory.
+func projectContaining(path string) (db.Project, string, bool) {
+	path = resolvePath(path)
+
+	var found db.Project
+	var foundRoot string
+	for _, p := range db.GetProjects() {
+		root := resolvePath(p.Path)
+		if len(root) <= len(foundRoot) {
+			continue
+		}
+		if path == root || isBelow(path, root) {
+			found, foundRoot = p, root
+		}
+	}
+	if foundRoot == "" {
+		return found, "", false
+	}
+
+	rel, err := filepath.Rel(foundRoot, path)
+	if err != nil {
+		rel = "."
+	}
+	return found, rel, true
+}
+
+// currentProject returns the project containing path, or the working
+// directory if path is empty.
+func currentProject(path string) (db.Project, string, error) {
+	if path == "" {
+		workDir, err := os.Getwd()
+		if err != nil {
+			return db.Project{}, "", exitErrorWrapper("could not retrieve current working directory: %s", err.Error())
+		}
+		path = workDir
+	}
+
+	p, rel, ok := projectContaining(path)
+	if !ok {
+		return p, "", exitErrorWrapper("'%s' is not inside a registered project", path)
+	}
+	return p, rel, nil
+}
+
+func printWhich(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return exitErrorWrapper("invalid number of arguments, expected 0 or 1")
+	}
+
+	p, _, err := currentProject(c.Args().First())
+	if err != nil {
+		return err
+	}
+
+	log(c, "%s", p.Name)
+	return nil
+}
+
+// hereRecord is how 'prj here' shows the current project with --format.
+type hereRecord struct {
+	Name        string
+	Root        string
+	Subpath     string
+	Categories  []string
+	Description string
+	Archived    bool
+}
+
+func printHere(c *cli.Context) error {
+	if c.NArg() != 0 {
+		return exitErrorWrapper("invalid number of arguments, expected none")
+	}
+
+	p, rel, err := currentProject("")
+	if err != nil {
+		return err
+	}
+
+	if c.String("format") != "" {
+		r := newProjectRecord(c, p)
+		return writeFormattedRecord(c, hereRecord{
+			Name:        r.Name,
+			Root:        r.Path,
+			Subpath:     filepath.ToSlash(rel),
+			Categories:  r.Categories,
+			Description: r.Description,
+			Archived:    r.Archived,
+		})
+	}
+
+	log(c, "Name: %s", p.Name)
+	log(c, "Root: %s", p.Path)
+	log(c, "Subpath: %s", filepath.ToSlash(rel))
+	if categories := getProjectCategories(c, p); len(categories) > 0 {
+		log(c, "Categories: %s", strings.Join(categories, "/"))
+	}
+	if p.Description != "" {
+		log(c, "Description: %s", p.Description)
+	}
+	if p.Archived {
+		log(c, "Archived: true")
+	}
+	return nil
+}